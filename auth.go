@@ -0,0 +1,150 @@
+// Copyright 2020 Damien PLÉNARD.
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+)
+
+const (
+	deviceCodeURL   = "https://id.twitch.tv/oauth2/device"
+	deviceTokenURL  = "https://id.twitch.tv/oauth2/token"
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var (
+	authCommand = kingpin.Command("auth",
+		"Obtain a user access token with the OAuth device code flow and write it to a file.")
+	authScopes = authCommand.Flag("auth.scope",
+		"Scope requested for the user access token, can be repeated.").
+		Default("channel:read:subscriptions", "bits:read", "channel:read:charity", "user:read:broadcast").Strings()
+	authOutput = authCommand.Flag("auth.output",
+		"File the access and refresh tokens are written to, to be passed to the exporter as @<file>.").
+		Default("twitch_tokens.args").String()
+)
+
+type deviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Message      string `json:"message"`
+}
+
+// runAuth obtains a user access token with the device code flow: the user is
+// asked to visit the verification URL while the token endpoint is polled until
+// the authorization is granted. The tokens are written as command line flags,
+// which kingpin reads back when the file is passed as @<file>.
+func runAuth(logger *slog.Logger) error {
+	scopes := strings.Join(*authScopes, " ")
+
+	var device deviceCodeResponse
+	if err := postForm(deviceCodeURL, url.Values{
+		"client_id": {*twitchClientID},
+		"scopes":    {scopes},
+	}, &device); err != nil {
+		return err
+	}
+
+	fmt.Printf("Open %s and enter the code %s to authorise the exporter.\n", device.VerificationURI, device.UserCode)
+
+	interval := time.Duration(device.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		var token deviceTokenResponse
+		err := postForm(deviceTokenURL, url.Values{
+			"client_id":     {*twitchClientID},
+			"client_secret": {*twitchClientSecret},
+			"scopes":        {scopes},
+			"device_code":   {device.DeviceCode},
+			"grant_type":    {deviceGrantType},
+		}, &token)
+
+		// the token endpoint answers with an error until the user authorised
+		// the exporter
+		if token.Message == "authorization_pending" {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		logger.Info("user access token obtained", "output", *authOutput)
+
+		return writeTokenFile(*authOutput, token.AccessToken, token.RefreshToken)
+	}
+
+	return errors.New("device code expired before the exporter was authorised")
+}
+
+// writeTokenFile writes the tokens as the command line flags setting them, one
+// per line, so the file can be passed to the exporter as @<file> or with
+// --twitch.token-file.
+func writeTokenFile(path, accessToken, refreshToken string) error {
+	return os.WriteFile(path, []byte(fmt.Sprintf(
+		"--twitch.access-token=%s\n--twitch.refresh-token=%s\n",
+		accessToken, refreshToken,
+	)), 0600)
+}
+
+// readTokenFile reads the tokens of a file written by writeTokenFile.
+func readTokenFile(path string) (accessToken, refreshToken string, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if token, ok := strings.CutPrefix(line, "--twitch.access-token="); ok {
+			accessToken = token
+		}
+
+		if token, ok := strings.CutPrefix(line, "--twitch.refresh-token="); ok {
+			refreshToken = token
+		}
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// postForm posts the form to endpoint and decodes the response body into data,
+// which is decoded even when an error status is returned, so callers can read
+// the error message.
+func postForm(endpoint string, form url.Values, data any) error {
+	resp, err := http.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, endpoint)
+	}
+
+	return nil
+}
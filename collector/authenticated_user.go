@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// authenticatedUser is the user an access token belongs to, it is resolved
+// once per access token so it is looked up again once the token is renewed.
+type authenticatedUser struct {
+	mtx    sync.Mutex
+	token  string
+	userID string
+	login  string
+}
+
+type authenticatedUserCollector struct {
+	logger *slog.Logger
+	client *helix.Client
+	user   *authenticatedUser
+
+	authenticatedUserInfo typedDesc
+}
+
+func init() {
+	registerUserCollector("authenticated_user", defaultDisabled, priorityNormal, NewAuthenticatedUserCollector)
+}
+
+func NewAuthenticatedUserCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := authenticatedUserCollector{
+		logger: logger,
+		client: client,
+		user:   &authenticatedUser{},
+
+		authenticatedUserInfo: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "authenticated_user_info"),
+			"The user the user access token belongs to.",
+			[]string{"id", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	if _, _, err := c.resolve(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// resolve returns the ID and login of the user the current access token
+// belongs to, validating the token when it changed since the last lookup. The
+// token itself is never exported nor logged.
+func (c authenticatedUserCollector) resolve() (string, string, error) {
+	c.user.mtx.Lock()
+	defer c.user.mtx.Unlock()
+
+	token := c.client.GetUserAccessToken()
+	if c.user.userID != "" && token == c.user.token {
+		return c.user.userID, c.user.login, nil
+	}
+
+	valid, tokenResp, err := c.client.ValidateToken(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !valid {
+		return "", "", errors.New("the user access token is not valid")
+	}
+
+	c.user.token = token
+	c.user.userID = tokenResp.Data.UserID
+	c.user.login = tokenResp.Data.Login
+
+	return c.user.userID, c.user.login, nil
+}
+
+func (c authenticatedUserCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	userID, login, err := c.resolve()
+	if err != nil {
+		logger.Error("Failed to validate the user access token against Twitch", "err", err)
+		return err
+	}
+
+	ch <- c.authenticatedUserInfo.mustNewConstMetric(1, userID, login)
+
+	return nil
+}
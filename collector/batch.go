@@ -0,0 +1,23 @@
+package collector
+
+// helixBatchSize is the maximum number of logins or IDs Helix accepts in a
+// single request to the endpoints which take several of them.
+const helixBatchSize = 100
+
+// inBatches calls request with the items split into batches of at most
+// helixBatchSize and joins the results, so requests for more than 100 channels
+// aren't silently truncated.
+func inBatches[T any](items []string, request func(batch []string) ([]T, error)) ([]T, error) {
+	results := []T{}
+
+	for start := 0; start < len(items); start += helixBatchSize {
+		batch, err := request(items[start:min(start+helixBatchSize, len(items))])
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestInBatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       int
+		wantBatches []int
+	}{
+		{name: "no items", items: 0, wantBatches: nil},
+		{name: "one batch", items: 99, wantBatches: []int{99}},
+		{name: "250 logins", items: 250, wantBatches: []int{100, 100, 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logins := []string{}
+			for i := range tt.items {
+				logins = append(logins, fmt.Sprintf("channel%d", i))
+			}
+
+			var batches []int
+			got, err := inBatches(logins, func(batch []string) ([]string, error) {
+				batches = append(batches, len(batch))
+				return batch, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", batches, tt.wantBatches)
+			}
+
+			if !slices.Equal(got, logins) {
+				t.Errorf("merged %d results, want the %d logins in order", len(got), len(logins))
+			}
+		})
+	}
+}
+
+func TestInBatchesError(t *testing.T) {
+	logins := make([]string, 250)
+	failure := errors.New("failure")
+
+	calls := 0
+	_, err := inBatches(logins, func(batch []string) ([]string, error) {
+		calls++
+		if calls == 2 {
+			return nil, failure
+		}
+		return batch, nil
+	})
+
+	if !errors.Is(err, failure) {
+		t.Errorf("err = %v, want the error of the failed batch", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("%d batches requested, want the requests to stop at the failed one", calls)
+	}
+}
@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type adBreakKey struct {
+	username    string
+	isAutomatic bool
+}
+
+var (
+	adBreaks      = map[adBreakKey]int{}
+	adBreaksEndAt = map[string]time.Time{}
+	adBreaksMutex = sync.Mutex{}
+)
+
+type channelAdBreaksCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelAdRunning     typedDesc
+	channelAdBreaksTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_ad_breaks", defaultDisabled, priorityNormal, NewChannelAdBreaksCollector)
+}
+
+// NewChannelAdBreaksCollector tracks the ad breaks of a channel. The
+// broadcaster must have granted the channel:read:ads scope.
+func NewChannelAdBreaksCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventsubClient.On("channel.ad_break.begin", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelAdBreakBeginEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel ad break event", "error", err)
+			return
+		}
+
+		adBreaksMutex.Lock()
+		defer adBreaksMutex.Unlock()
+
+		adBreaks[adBreakKey{username: event.BroadcasterUserLogin, isAutomatic: event.IsAutomatic}]++
+
+		// the ad break is running until its duration elapsed, there is no
+		// event for its end
+		adBreaksEndAt[event.BroadcasterUserLogin] = event.StartedAt.Add(time.Duration(event.DurationSeconds) * time.Second)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		err := eventsubClient.SubscribeWithCondition("channel.ad_break.begin", "1", broadcasterID, helix.EventSubCondition{
+			BroadcasterUserID: broadcasterID,
+		})
+		if err != nil {
+			logger.Error("failed to subscribe to channel ad breaks", "error", err)
+		}
+	}
+
+	c := channelAdBreaksCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelAdRunning: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_ad_running"),
+			"Is an ad break running on the channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelAdBreaksTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_ad_breaks_total"),
+			"The number of ad breaks run on a channel, by whether they were run automatically.",
+			[]string{"username", "login", "is_automatic"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+func (c channelAdBreaksCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	adBreaksMutex.Lock()
+	defer adBreaksMutex.Unlock()
+
+	now := time.Now()
+	for _, n := range c.channelNames {
+		login := strings.ToLower(n)
+		if _, ok := displayNames[login]; !ok {
+			continue
+		}
+
+		running := 0
+		if now.Before(adBreaksEndAt[login]) {
+			running = 1
+		}
+
+		ch <- c.channelAdRunning.mustNewConstMetric(float64(running), displayNames[login], login)
+	}
+
+	for key, count := range adBreaks {
+		ch <- c.channelAdBreaksTotal.mustNewConstMetric(float64(count), displayNames[key.username], key.username, strconv.FormatBool(key.isAutomatic))
+	}
+
+	return nil
+}
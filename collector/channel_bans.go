@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type banKey struct {
+	username  string
+	permanent bool
+}
+
+var (
+	bans      = map[banKey]int{}
+	timeouts  = map[string]int{}
+	unbans    = map[string]int{}
+	bansMutex = sync.Mutex{}
+)
+
+type channelBansCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelBansTotal     typedDesc
+	channelTimeoutsTotal typedDesc
+	channelUnbansTotal   typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_bans", defaultDisabled, priorityNormal, NewChannelBansCollector)
+}
+
+// NewChannelBansCollector counts the bans, timeouts and unbans of a channel.
+// The broadcaster must have granted the channel:moderate scope.
+func NewChannelBansCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventsubClient.On("channel.ban", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelBanEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel ban event", "error", err)
+			return
+		}
+
+		bansMutex.Lock()
+		defer bansMutex.Unlock()
+
+		bans[banKey{username: event.BroadcasterUserLogin, permanent: event.IsPermanent}]++
+
+		if !event.IsPermanent {
+			timeouts[event.BroadcasterUserLogin]++
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventsubClient.On("channel.unban", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelUnbanEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel unban event", "error", err)
+			return
+		}
+
+		bansMutex.Lock()
+		defer bansMutex.Unlock()
+
+		unbans[event.BroadcasterUserLogin]++
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		for _, event := range []string{"channel.ban", "channel.unban"} {
+			err := eventsubClient.SubscribeWithCondition(event, "1", broadcasterID, helix.EventSubCondition{
+				BroadcasterUserID: broadcasterID,
+			})
+			if err != nil {
+				logger.Error("failed to subscribe to channel ban events", "event", event, "error", err)
+			}
+		}
+	}
+
+	c := channelBansCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelBansTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_bans_total"),
+			"The number of users banned from a channel, including timeouts as non permanent bans.",
+			[]string{"username", "login", "permanent"}, nil,
+		), prometheus.CounterValue},
+		channelTimeoutsTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_timeouts_total"),
+			"The number of users timed out in a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.CounterValue},
+		channelUnbansTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_unbans_total"),
+			"The number of users unbanned from a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+func (c channelBansCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+
+	for key, count := range bans {
+		ch <- c.channelBansTotal.mustNewConstMetric(float64(count), displayNames[key.username], key.username, strconv.FormatBool(key.permanent))
+	}
+
+	for login, count := range timeouts {
+		ch <- c.channelTimeoutsTotal.mustNewConstMetric(float64(count), displayNames[login], login)
+	}
+
+	for login, count := range unbans {
+		ch <- c.channelUnbansTotal.mustNewConstMetric(float64(count), displayNames[login], login)
+	}
+
+	return nil
+}
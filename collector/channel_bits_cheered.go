@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	bitsCheered      = map[string]int{}
+	bitsCheeredMutex = sync.Mutex{}
+)
+
+type channelBitsCheeredCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelBitsCheeredTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_bits_cheered", defaultDisabled, priorityNormal, NewChannelBitsCheeredCollector)
+}
+
+func NewChannelBitsCheeredCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// cheers are read from the chat messages, which unlike the bits
+	// leaderboard does not require the bits:read scope
+	err = eventsubClient.On("channel.chat.message", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelChatMessageEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel chat message event", "error", err)
+			return
+		}
+
+		if event.Cheer == nil {
+			return
+		}
+
+		bitsCheeredMutex.Lock()
+		defer bitsCheeredMutex.Unlock()
+
+		bitsCheered[event.BroadcasterUserLogin] += event.Cheer.Bits
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		err := eventsubClient.Subscribe("channel.chat.message", broadcasterID)
+		if err != nil {
+			logger.Error("failed to subscribe to channel chat messages", "error", err)
+		}
+	}
+
+	c := channelBitsCheeredCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelBitsCheeredTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_bits_cheered_total"),
+			"The number of bits cheered in the chat of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+func (c channelBitsCheeredCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	bitsCheeredMutex.Lock()
+	defer bitsCheeredMutex.Unlock()
+
+	for login, bits := range bitsCheered {
+		ch <- c.channelBitsCheeredTotal.mustNewConstMetric(float64(bits), displayNames[login], login)
+	}
+
+	return nil
+}
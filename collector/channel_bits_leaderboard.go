@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	bitsPeriod = kingpin.Flag("twitch.bits-period",
+		"Period of the bits leaderboard, one of day, week, month, year or all.").
+		Default("all").Enum("day", "week", "month", "year", "all")
+	bitsLeaderboardCount = kingpin.Flag("twitch.bits-leaderboard-count",
+		"Number of ranks of the bits leaderboard to export, at most 100.").
+		Default("10").Int()
+)
+
+type channelBitsLeaderboardCollector struct {
+	logger *slog.Logger
+	client *helix.Client
+	login  string
+
+	channelBitsLeaderboard typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token with the
+	// bits:read scope of the broadcaster
+	registerUserCollector("channel_bits_leaderboard", defaultDisabled, priorityNormal, NewChannelBitsLeaderboardCollector)
+}
+
+func NewChannelBitsLeaderboardCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if *bitsLeaderboardCount < 1 || *bitsLeaderboardCount > 100 {
+		return nil, errors.New("bits leaderboard count must be between 1 and 100")
+	}
+
+	// the leaderboard is only available for the broadcaster the access token
+	// belongs to, so it is looked up rather than taken from the channel names
+	valid, tokenResp, err := client.ValidateToken(client.GetUserAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, errors.New("a valid user access token is required for the bits leaderboard")
+	}
+
+	c := channelBitsLeaderboardCollector{
+		logger: logger,
+		client: client,
+		login:  tokenResp.Data.Login,
+
+		channelBitsLeaderboard: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_bits_leaderboard"),
+			"The amount of bits cheered by the top cheerers of a channel over the configured period.",
+			[]string{"username", "login", "rank", "cheerer"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelBitsLeaderboardCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	leaderboardResp, err := c.client.GetBitsLeaderboard(&helix.BitsLeaderboardParams{
+		Count:  *bitsLeaderboardCount,
+		Period: *bitsPeriod,
+	})
+
+	if err != nil {
+		logger.Error("Failed to collect bits leaderboard from Twitch helix API", "err", err)
+		return err
+	}
+
+	if leaderboardResp.StatusCode != 200 {
+		logger.Error("Failed to collect bits leaderboard from Twitch helix API", "err", leaderboardResp.ErrorMessage)
+		return helixError(leaderboardResp.StatusCode, leaderboardResp.ErrorMessage)
+	}
+
+	displayNames, err := getDisplayNames(c.client, []string{c.login})
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, entry := range leaderboardResp.Data.UserBitTotals {
+		ch <- c.channelBitsLeaderboard.mustNewConstMetric(float64(entry.Score), displayNames[c.login], c.login, strconv.Itoa(entry.Rank), entry.UserLogin)
+	}
+
+	return nil
+}
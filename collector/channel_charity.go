@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type channelCharityCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelCharityCurrentAmount typedDesc
+	channelCharityTargetAmount  typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token with the
+	// channel:read:charity scope of the broadcaster
+	registerUserCollector("channel_charity", defaultDisabled, priorityNormal, NewChannelCharityCollector)
+}
+
+func NewChannelCharityCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelCharityCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelCharityCurrentAmount: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_charity_current_amount"),
+			"The amount raised by the active charity campaign of a channel, in the minor units of the currency. Requires the channel:read:charity scope.",
+			[]string{"username", "login", "charity_name", "currency"}, nil,
+		), prometheus.GaugeValue},
+		channelCharityTargetAmount: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_charity_target_amount"),
+			"The target of the active charity campaign of a channel, in the minor units of the currency. Requires the channel:read:charity scope.",
+			[]string{"username", "login", "charity_name", "currency"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelCharityCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		charityResp, err := c.client.GetCharityCampaigns(&helix.CharityCampaignsParams{
+			BroadcasterID: user.ID,
+		})
+
+		if err != nil {
+			logger.Error("Failed to collect charity stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		if charityResp.StatusCode != 200 {
+			logger.Error("Failed to collect charity stats from Twitch helix API", "err", charityResp.ErrorMessage)
+			return helixError(charityResp.StatusCode, charityResp.ErrorMessage)
+		}
+
+		// channels without an active campaign return no data
+		for _, campaign := range charityResp.Data.Campaigns {
+			ch <- c.channelCharityCurrentAmount.mustNewConstMetric(float64(campaign.CurrentAmount.Value), user.DisplayName, user.Login, campaign.Name, campaign.CurrentAmount.Currency)
+			ch <- c.channelCharityTargetAmount.mustNewConstMetric(float64(campaign.TargetAmount.Value), user.DisplayName, user.Login, campaign.Name, campaign.TargetAmount.Currency)
+		}
+	}
+
+	return nil
+}
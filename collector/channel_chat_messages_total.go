@@ -1,58 +1,57 @@
 package collector
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
+	"maps"
+	"strings"
 	"sync"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/damoun/twitch_exporter/internal/hll"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
-	chatMessages      = MessageCounter{}
-	chatMessagesMutex = sync.Mutex{}
+	chatCountByChatter = kingpin.Flag("chat.count-by-chatter",
+		"Also count the chat messages of each chatter, which has a high cardinality on busy channels.").
+		Default("false").Bool()
+	chatCountUniqueChatters = kingpin.Flag("chat.count-unique-chatters",
+		"Estimate the number of distinct chatters of each channel between scrapes, at a fixed cardinality.").
+		Default("false").Bool()
 )
 
-type MessageCounter map[string]map[string]int
-
-func (m MessageCounter) Add(username string, chatterUsername string) {
-	m.ensure(username, chatterUsername)
-
-	chatMessagesMutex.Lock()
-	defer chatMessagesMutex.Unlock()
-
-	chatMessages[username][chatterUsername]++
+// chatMessageKey is what the chat messages of a channel are counted by, the
+// number of message types being small enough to keep them apart.
+type chatMessageKey struct {
+	username    string
+	messageType string
 }
 
-func (m MessageCounter) Reset(username string, chatterUsername string) {
-	m.ensure(username, chatterUsername)
-
-	chatMessagesMutex.Lock()
-	defer chatMessagesMutex.Unlock()
-
-	chatMessages[username][chatterUsername] = 0
-}
+var (
+	chatMessages        = MessageCounter{}
+	channelChatMessages = map[chatMessageKey]int{}
+	chatMessagesMutex   = sync.Mutex{}
+	// uniqueChatters estimates the distinct chatters of each channel since
+	// the previous scrape, keyed by login
+	uniqueChatters = map[string]*hll.Sketch{}
+)
 
-// ensure ensures that the username and chatterUsername exist in the map
-func (m MessageCounter) ensure(username string, chatterUsername string) {
-	if _, ok := chatMessages[username]; !ok {
-		chatMessages[username] = make(map[string]int)
-	}
+// MessageCounter counts the chat messages of each chatter per channel. The
+// counts are never reset, so they are exported as counters.
+type MessageCounter map[string]map[string]int
 
-	if _, ok := chatMessages[username][chatterUsername]; !ok {
-		chatMessages[username][chatterUsername] = 0
+// Add counts a message of the chatter in the channel of username. The caller
+// must hold chatMessagesMutex.
+func (m MessageCounter) Add(username string, chatterUsername string) {
+	if _, ok := m[username]; !ok {
+		m[username] = make(map[string]int)
 	}
-}
-
-func (m MessageCounter) Get(username string, chatterUsername string) int {
-	m.ensure(username, chatterUsername)
 
-	chatMessagesMutex.Lock()
-	defer chatMessagesMutex.Unlock()
-
-	return chatMessages[username][chatterUsername]
+	m[username][chatterUsername]++
 }
 
 type ChannelChatMessagesCollector struct {
@@ -60,13 +59,15 @@ type ChannelChatMessagesCollector struct {
 	client       *helix.Client
 	channelNames ChannelNames
 
-	channelChatMessages typedDesc
+	channelChatMessages    typedDesc
+	channelChatterMessages typedDesc
+	channelUniqueChatters  typedDesc
 }
 
 func init() {
 	// disabled by default since you need to use webhooks to listen for events using an app access token
 	// which requires it to be exposed to the internet
-	registerCollector("channel_chat_messages_total", defaultDisabled, NewChannelChatMessagesCollector)
+	registerCollector("channel_chat_messages_total", defaultDisabled, priorityNormal, NewChannelChatMessagesCollector)
 }
 
 func NewChannelChatMessagesCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
@@ -75,18 +76,11 @@ func NewChannelChatMessagesCollector(logger *slog.Logger, client *helix.Client,
 		return nil, eventsub.ErrEventsubClientNotSet
 	}
 
-	broadcasterIDs := []string{}
-	users, err := client.GetUsers(&helix.UsersParams{
-		Logins: channelNames,
-	})
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, user := range users.Data.Users {
-		broadcasterIDs = append(broadcasterIDs, user.ID)
-	}
-
 	err = eventsubClient.On("channel.chat.message", func(eventRaw json.RawMessage) {
 		var event eventsub.ChannelChatMessageEvent
 
@@ -95,12 +89,22 @@ func NewChannelChatMessagesCollector(logger *slog.Logger, client *helix.Client,
 			return
 		}
 
-		chatMessages.Add(event.BroadcasterUserLogin, event.ChatterUserLogin)
+		chatMessagesMutex.Lock()
+		defer chatMessagesMutex.Unlock()
 
-		logger.Info(
-			"channel chat message",
-			"count", chatMessages.Get(event.BroadcasterUserLogin, event.ChatterUserLogin),
-		)
+		channelChatMessages[chatMessageKey{username: event.BroadcasterUserLogin, messageType: event.MessageType}]++
+
+		if *chatCountByChatter {
+			chatMessages.Add(event.BroadcasterUserLogin, event.ChatterUserLogin)
+		}
+
+		if *chatCountUniqueChatters {
+			if _, ok := uniqueChatters[event.BroadcasterUserLogin]; !ok {
+				uniqueChatters[event.BroadcasterUserLogin] = hll.New()
+			}
+
+			uniqueChatters[event.BroadcasterUserLogin].Add(event.ChatterUserID)
+		}
 	})
 
 	// todo: we can only subscribe to broadcasters with an access token and refresh token, so this
@@ -123,30 +127,85 @@ func NewChannelChatMessagesCollector(logger *slog.Logger, client *helix.Client,
 		client:       client,
 		channelNames: channelNames,
 
+		channelChatMessages: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_chat_messages_total"),
+			"The number of chat messages sent in a channel, by message type.",
+			[]string{"username", "login", "message_type"}, nil,
+		), prometheus.CounterValue},
 		// we keep the use of the username as the label to avoid adding a bunch of duplicate labels under
 		// a new name of broadcaster_username, which would just match with the other metrics using username
 		// however to group by the chatters we provide chatter_username as a label.
-		// this metric would increase label cardinality a lot for larger channels, so it should be used with
-		// care and ideally only on a small subset of channels.
-		channelChatMessages: typedDesc{prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "channel_chat_messages_total"),
-			"The number of chat messages sent in a channel.",
-			[]string{"username", "chatter_username"}, nil,
+		// this metric would increase label cardinality a lot for larger channels, so it is only exported
+		// with --chat.count-by-chatter and should ideally be used on a small subset of channels.
+		channelChatterMessages: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_chatter_messages_total"),
+			"The number of chat messages sent in a channel by a chatter.",
+			[]string{"username", "login", "chatter_username"}, nil,
+		), prometheus.CounterValue},
+		// the distinct chatters are estimated with a HyperLogLog sketch, which
+		// has a fixed size whatever the number of chatters
+		channelUniqueChatters: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_unique_chatters"),
+			"The estimated number of distinct chatters of a channel since the previous scrape.",
+			[]string{"username", "login"}, nil,
 		), prometheus.GaugeValue},
 	}
 
 	return c, nil
 }
 
-func (c ChannelChatMessagesCollector) Update(ch chan<- prometheus.Metric) error {
+func (c ChannelChatMessagesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
 	if len(c.channelNames) == 0 {
 		return ErrNoData
 	}
 
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	// the counts are copied under the lock, so the chat messages are not
+	// held up while the metrics are sent to the scrape
+	chatMessagesMutex.Lock()
+	messages := maps.Clone(channelChatMessages)
+	chatterMessages := make(map[string]map[string]int, len(chatMessages))
+	for login, counts := range chatMessages {
+		chatterMessages[login] = maps.Clone(counts)
+	}
+
+	chatters := make(map[string]uint64)
+	if *chatCountUniqueChatters {
+		// the sketches are reset on every scrape, so each estimate covers
+		// the chatters since the previous one
+		for _, n := range c.channelNames {
+			login := strings.ToLower(n)
+
+			if sketch, ok := uniqueChatters[login]; ok {
+				chatters[login] = sketch.Estimate()
+				delete(uniqueChatters, login)
+			}
+		}
+	}
+	chatMessagesMutex.Unlock()
+
 	// loop all the channels and push the counts
-	for username, count := range chatMessages {
+	for key, count := range messages {
+		ch <- c.channelChatMessages.mustNewConstMetric(float64(count), displayNames[key.username], key.username, key.messageType)
+	}
+
+	for login, count := range chatterMessages {
 		for chatterUsername, count := range count {
-			ch <- prometheus.MustNewConstMetric(c.channelChatMessages.desc, prometheus.CounterValue, float64(count), username, chatterUsername)
+			ch <- c.channelChatterMessages.mustNewConstMetric(float64(count), displayNames[login], login, chatterUsername)
+		}
+	}
+
+	if *chatCountUniqueChatters {
+		for _, n := range c.channelNames {
+			login := strings.ToLower(n)
+			ch <- c.channelUniqueChatters.mustNewConstMetric(float64(chatters[login]), displayNames[login], login)
 		}
 	}
 
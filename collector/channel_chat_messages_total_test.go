@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestChannelChatMessagesUpdateUnlocked(t *testing.T) {
+	server := testutil.NewServer(testutil.DefaultFixtures)
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chatMessagesMutex.Lock()
+	channelChatMessages[chatMessageKey{username: "somechannel", messageType: "text"}] = 3
+	chatMessages.Add("somechannel", "viewer")
+	chatMessagesMutex.Unlock()
+	defer func() {
+		chatMessagesMutex.Lock()
+		delete(channelChatMessages, chatMessageKey{username: "somechannel", messageType: "text"})
+		delete(chatMessages, "somechannel")
+		chatMessagesMutex.Unlock()
+	}()
+
+	c := ChannelChatMessagesCollector{
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		client:       client,
+		channelNames: ChannelNames{"somechannel"},
+
+		channelChatMessages:    typedDesc{prometheus.NewDesc("test_chat_messages", "Test.", []string{"username", "login", "message_type"}, nil), prometheus.CounterValue},
+		channelChatterMessages: typedDesc{prometheus.NewDesc("test_chatter_messages", "Test.", []string{"username", "login", "chatter_username"}, nil), prometheus.CounterValue},
+		channelUniqueChatters:  typedDesc{prometheus.NewDesc("test_unique_chatters", "Test.", []string{"username", "login"}, nil), prometheus.GaugeValue},
+	}
+
+	assertUnlockedWhileSending(t, &chatMessagesMutex, func(ch chan<- prometheus.Metric) error {
+		return c.Update(t.Context(), ch)
+	})
+}
@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	clipsWindow = kingpin.Flag("twitch.clips-window",
+		"Time window over which clips are counted for the channel_clips_total collector.").
+		Default("24h").Duration()
+	clipsMaxPages = kingpin.Flag("twitch.clips-max-pages",
+		"Maximum number of pages of clips to read per channel on each scrape.").
+		Default("10").Int()
+)
+
+// clipsBuckets are the windows clips are counted over besides the clips
+// window, the ones longer than the clips window are left out.
+var clipsBuckets = []time.Duration{time.Hour, 6 * time.Hour}
+
+// clipsBucketWindows returns the windows clips are counted over, from the
+// shortest to the clips window.
+func clipsBucketWindows() []time.Duration {
+	windows := []time.Duration{}
+	for _, bucket := range clipsBuckets {
+		if bucket < *clipsWindow {
+			windows = append(windows, bucket)
+		}
+	}
+
+	return append(windows, *clipsWindow)
+}
+
+// clipsBucketLabel formats the window of a bucket, in hours when it is a
+// whole number of hours.
+func clipsBucketLabel(window time.Duration) string {
+	if window%time.Hour == 0 {
+		return fmt.Sprintf("%dh", window/time.Hour)
+	}
+
+	return window.String()
+}
+
+// clipsRateLimitFloor is the number of remaining helix requests under which the
+// clips walk stops early, leaving room for the other collectors.
+const clipsRateLimitFloor = 10
+
+type channelClipsTotalCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelClipsTotal typedDesc
+}
+
+func init() {
+	// disabled by default since walking the clips of a busy channel can cost a
+	// lot of requests per scrape
+	registerCollector("channel_clips_total", defaultDisabled, priorityLow, NewChannelClipsTotalCollector)
+}
+
+func NewChannelClipsTotalCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelClipsTotalCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelClipsTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_clips_total"),
+			"The number of clips created for a channel within the bucket, the last 1h, 6h and the configured window.",
+			[]string{"username", "login", "bucket"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelClipsTotalCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	windows := clipsBucketWindows()
+	endedAt := time.Now()
+
+	for _, user := range users {
+		counts, err := c.getClipsCount(ctx, logger, user.ID, windows, endedAt)
+		if err != nil {
+			logger.Error("Failed to collect clips stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		for i, window := range windows {
+			ch <- c.channelClipsTotal.mustNewConstMetric(float64(counts[i]), user.DisplayName, user.Login, clipsBucketLabel(window))
+		}
+	}
+
+	return nil
+}
+
+// getClipsCount counts the clips of a broadcaster created within each window
+// before endedAt, in a single walk over the clips of the longest window. It
+// follows the pagination cursor for at most --twitch.clips-max-pages pages.
+// When the page cap or the rate limit floor is reached the partial counts are
+// returned, when the scrape gives up on the collector the walk stops.
+func (c channelClipsTotalCollector) getClipsCount(ctx context.Context, logger *slog.Logger, broadcasterID string, windows []time.Duration, endedAt time.Time) ([]int, error) {
+	counts := make([]int, len(windows))
+	startedAt := endedAt.Add(-windows[len(windows)-1])
+	cursor := ""
+
+	for page := 1; ; page++ {
+		// the scrape gave up on the collector, such as when it timed out
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		clipsResp, err := c.client.GetClips(&helix.ClipsParams{
+			BroadcasterID: broadcasterID,
+			First:         100,
+			After:         cursor,
+			StartedAt:     helix.Time{Time: startedAt},
+			EndedAt:       helix.Time{Time: endedAt},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if clipsResp.StatusCode != 200 {
+			return nil, helixError(clipsResp.StatusCode, clipsResp.ErrorMessage)
+		}
+
+		for _, clip := range clipsResp.Data.Clips {
+			countClip(logger, counts, windows, clip, endedAt)
+		}
+
+		cursor = clipsResp.Data.Pagination.Cursor
+
+		if cursor == "" {
+			return counts, nil
+		}
+
+		if page >= *clipsMaxPages {
+			logger.Warn("clips page limit reached, returning partial count", "broadcaster_id", broadcasterID, "pages", page, "counts", counts)
+			return counts, nil
+		}
+
+		// responses without rate limit headers report a limit of 0, skip the
+		// check for those rather than stopping after the first page
+		if remaining := clipsResp.GetRateLimitRemaining(); clipsResp.GetRateLimit() > 0 && remaining < clipsRateLimitFloor {
+			logger.Warn("rate limit almost exhausted, returning partial clips count", "broadcaster_id", broadcasterID, "pages", page, "counts", counts, "remaining", remaining)
+			return counts, nil
+		}
+	}
+}
+
+// countClip counts a clip in every window it was created within. The clips
+// are requested for the longest window, so a clip which creation time cannot
+// be parsed is only counted in it, and logged since the shorter windows may
+// miss it.
+func countClip(logger *slog.Logger, counts []int, windows []time.Duration, clip helix.Clip, endedAt time.Time) {
+	created, err := time.Parse(time.RFC3339, clip.CreatedAt)
+	if err != nil {
+		logger.Warn("could not parse the creation time of a clip, only counting it in the longest window", "clip_id", clip.ID, "created_at", clip.CreatedAt, "err", err)
+		counts[len(counts)-1]++
+		return
+	}
+
+	age := endedAt.Sub(created)
+	for i, window := range windows {
+		if age <= window {
+			counts[i]++
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/nicklaw5/helix/v2"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// clipsFixture returns a page of clips of somechannel created the given
+// durations ago, with the cursor of the next page.
+func clipsFixture(cursor string, ages ...time.Duration) string {
+	clips := []string{}
+	for i, age := range ages {
+		clips = append(clips, fmt.Sprintf(`{"id":"Clip%d","broadcaster_id":"1234","broadcaster_name":"SomeChannel","title":"Clip","view_count":10,"created_at":%q,"duration":30}`, i, time.Now().Add(-age).UTC().Format(time.RFC3339)))
+	}
+
+	return fmt.Sprintf(`{"data":[%s],"pagination":{"cursor":%q}}`, strings.Join(clips, ","), cursor)
+}
+
+// setClipsFlags sets the clips flags for the duration of a test.
+func setClipsFlags(t *testing.T, window time.Duration, maxPages int) {
+	previousWindow, previousMaxPages := *clipsWindow, *clipsMaxPages
+	*clipsWindow, *clipsMaxPages = window, maxPages
+	t.Cleanup(func() { *clipsWindow, *clipsMaxPages = previousWindow, previousMaxPages })
+}
+
+func TestChannelClipsTotalCollector(t *testing.T) {
+	tests := []struct {
+		name string
+		ages []time.Duration
+		want [3]int
+	}{
+		{name: "no clips", want: [3]int{0, 0, 0}},
+		{name: "recent clip", ages: []time.Duration{10 * time.Minute}, want: [3]int{1, 1, 1}},
+		{name: "clips across buckets", ages: []time.Duration{10 * time.Minute, 3 * time.Hour, 12 * time.Hour, 20 * time.Hour}, want: [3]int{1, 2, 4}},
+	}
+
+	setClipsFlags(t, 24*time.Hour, 10)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixtures := maps.Clone(testutil.DefaultFixtures)
+			fixtures["/clips"] = clipsFixture("", tt.ages...)
+
+			want := fmt.Sprintf(`
+# HELP twitch_channel_clips_total The number of clips created for a channel within the bucket, the last 1h, 6h and the configured window.
+# TYPE twitch_channel_clips_total gauge
+twitch_channel_clips_total{bucket="1h",login="somechannel",username="SomeChannel"} %d
+twitch_channel_clips_total{bucket="6h",login="somechannel",username="SomeChannel"} %d
+twitch_channel_clips_total{bucket="24h",login="somechannel",username="SomeChannel"} %d
+`, tt.want[0], tt.want[1], tt.want[2])
+
+			c := newTestCollector(t, NewChannelClipsTotalCollector, fixtures, "somechannel")
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCountClip(t *testing.T) {
+	endedAt := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	windows := []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+	tests := []struct {
+		name      string
+		createdAt []string
+		want      []int
+	}{
+		{name: "within the shortest window", createdAt: []string{"2026-01-01T23:30:00Z"}, want: []int{1, 1, 1}},
+		{name: "within the middle window", createdAt: []string{"2026-01-01T21:00:00Z"}, want: []int{0, 1, 1}},
+		{name: "within the longest window", createdAt: []string{"2026-01-01T12:00:00Z"}, want: []int{0, 0, 1}},
+		{name: "on the edge of a window", createdAt: []string{"2026-01-01T23:00:00Z"}, want: []int{1, 1, 1}},
+		{name: "unparseable creation time", createdAt: []string{"yesterday"}, want: []int{0, 0, 1}},
+		{
+			name:      "across buckets",
+			createdAt: []string{"2026-01-01T23:30:00Z", "2026-01-01T23:59:00Z", "2026-01-01T20:00:00Z", "2026-01-01T01:00:00Z", "yesterday"},
+			want:      []int{2, 3, 5},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := make([]int, len(windows))
+			for _, createdAt := range tt.createdAt {
+				countClip(logger, counts, windows, helix.Clip{ID: "clip", CreatedAt: createdAt}, endedAt)
+			}
+
+			if !slices.Equal(counts, tt.want) {
+				t.Errorf("counts = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetClipsCountPagination(t *testing.T) {
+	// three pages of clips, linked by their cursor
+	pages := map[string]string{
+		"":      clipsFixture("page2", 10*time.Minute, 2*time.Hour),
+		"page2": clipsFixture("page3", 30*time.Minute, 12*time.Hour),
+		"page3": clipsFixture("", 20*time.Hour),
+	}
+
+	tests := []struct {
+		name      string
+		maxPages  int
+		wantPages int
+		want      []int
+	}{
+		{name: "every page", maxPages: 10, wantPages: 3, want: []int{2, 3, 5}},
+		{name: "page limit", maxPages: 2, wantPages: 2, want: []int{2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setClipsFlags(t, 24*time.Hour, tt.maxPages)
+
+			requested := 0
+			var window time.Duration
+			handler := testutil.Handler(testutil.DefaultFixtures)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/clips" {
+					handler.ServeHTTP(w, r)
+					return
+				}
+
+				requested++
+				startedAt, _ := time.Parse(time.RFC3339, r.URL.Query().Get("started_at"))
+				endedAt, _ := time.Parse(time.RFC3339, r.URL.Query().Get("ended_at"))
+				window = endedAt.Sub(startedAt)
+
+				w.Write([]byte(pages[r.URL.Query().Get("after")]))
+			}))
+			defer server.Close()
+
+			client, err := testutil.NewClient(server)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := channelClipsTotalCollector{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), client: client}
+			counts, err := c.getClipsCount(context.Background(), c.logger, "1234", clipsBucketWindows(), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(counts, tt.want) {
+				t.Errorf("counts = %v, want %v", counts, tt.want)
+			}
+
+			if requested != tt.wantPages {
+				t.Errorf("%d pages requested, want %d", requested, tt.wantPages)
+			}
+
+			if window != 24*time.Hour {
+				t.Errorf("clips requested over %s, want the clips window", window)
+			}
+		})
+	}
+}
+
+func TestGetClipsCountCanceled(t *testing.T) {
+	setClipsFlags(t, 24*time.Hour, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the scrape gives up on the collector while the first page is served
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		cancel()
+		w.Write([]byte(clipsFixture("next", 10*time.Minute)))
+	}))
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := channelClipsTotalCollector{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), client: client}
+	if _, err := c.getClipsCount(ctx, c.logger, "1234", clipsBucketWindows(), time.Now()); !errors.Is(err, context.Canceled) {
+		t.Errorf("getClipsCount() error = %v, want %v", err, context.Canceled)
+	}
+
+	if requested != 1 {
+		t.Errorf("%d pages requested, want 1", requested)
+	}
+}
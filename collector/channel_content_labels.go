@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type channelContentLabels struct {
+	BroadcasterID               string   `json:"broadcaster_id"`
+	BroadcasterLogin            string   `json:"broadcaster_login"`
+	BroadcasterName             string   `json:"broadcaster_name"`
+	ContentClassificationLabels []string `json:"content_classification_labels"`
+}
+
+type channelContentLabelsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	clientID     string
+	channelNames ChannelNames
+
+	channelContentLabel typedDesc
+}
+
+func init() {
+	registerCollector("channel_content_labels", defaultDisabled, priorityNormal, NewChannelContentLabelsCollector)
+}
+
+func NewChannelContentLabelsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	// the helix client does not decode the content classification labels of
+	// the channel information, so it is requested directly
+	clientID, err := getHelixClientID(client)
+	if err != nil {
+		return nil, err
+	}
+
+	c := channelContentLabelsCollector{
+		logger:       logger,
+		client:       client,
+		clientID:     clientID,
+		channelNames: channelNames,
+
+		channelContentLabel: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_content_label"),
+			"The content classification labels applied to a channel.",
+			[]string{"username", "login", "label"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelContentLabelsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	query := url.Values{}
+	for _, user := range users {
+		query.Add("broadcaster_id", user.ID)
+	}
+
+	var channelsResp struct {
+		Data []channelContentLabels `json:"data"`
+	}
+
+	if _, err := helixGet(c.client, c.clientID, "/channels", query, &channelsResp); err != nil {
+		logger.Error("Failed to collect channel stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, channel := range channelsResp.Data {
+		for _, label := range channel.ContentClassificationLabels {
+			ch <- c.channelContentLabel.mustNewConstMetric(1, channel.BroadcasterName, channel.BroadcasterLogin, label)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/nicklaw5/helix/v2"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// authClient sends the requests of the helix client to the fake Helix API,
+// including the token validation which always targets helix.AuthBaseURL.
+type authClient struct {
+	server *httptest.Server
+}
+
+func (c authClient) Do(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(c.server.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	req.URL.Path = strings.TrimPrefix(req.URL.Path, "/oauth2")
+
+	return c.server.Client().Do(req)
+}
+
+func TestChannelContentLabelsCollector(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels string
+		want     string
+	}{
+		{
+			name:     "two labels",
+			channels: `{"data":[{"broadcaster_id":"1234","broadcaster_login":"somechannel","broadcaster_name":"SomeChannel","content_classification_labels":["Gambling","ProfanityVulgarity"]}]}`,
+			want: `
+# HELP twitch_channel_content_label The content classification labels applied to a channel.
+# TYPE twitch_channel_content_label gauge
+twitch_channel_content_label{label="Gambling",login="somechannel",username="SomeChannel"} 1
+twitch_channel_content_label{label="ProfanityVulgarity",login="somechannel",username="SomeChannel"} 1
+`,
+		},
+		{
+			name:     "no label",
+			channels: `{"data":[{"broadcaster_id":"1234","broadcaster_login":"somechannel","broadcaster_name":"SomeChannel","content_classification_labels":[]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixtures := maps.Clone(testutil.DefaultFixtures)
+			fixtures["/channels"] = tt.channels
+			fixtures["/validate"] = `{"client_id":"client-id","login":"","scopes":[],"user_id":"","expires_in":3600}`
+
+			server := testutil.NewServer(fixtures)
+			defer server.Close()
+
+			previousURL, previousClient := APIBaseURL, HTTPClient
+			APIBaseURL, HTTPClient = server.URL, server.Client()
+			defer func() { APIBaseURL, HTTPClient = previousURL, previousClient }()
+
+			client, err := helix.NewClient(&helix.Options{
+				ClientID:       "client-id",
+				AppAccessToken: "app-access-token",
+				APIBaseURL:     server.URL,
+				HTTPClient:     authClient{server: server},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			collector, err := NewChannelContentLabelsCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), client, nil, ChannelNames{"somechannel"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := testCollector{t: t, collector: collector}
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(tt.want), "twitch_channel_content_label"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
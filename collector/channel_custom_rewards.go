@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// customRewardsCacheTTL is how long the custom rewards of a channel are cached
+// for, since they rarely change.
+const customRewardsCacheTTL = 5 * time.Minute
+
+var customRewardsCache = cache.DefaultCache.Named("custom_reward")
+
+var customRewardsMaxLabeled = kingpin.Flag("twitch.custom-rewards-max-labeled",
+	"Maximum number of custom rewards of a channel exported with their cost, the most expensive first.").
+	Default("25").Int()
+
+type channelCustomRewardsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+	// scopeWarned holds the logins already warned about lacking the scope
+	// to read their rewards, so it is only logged once per channel
+	scopeWarned *sync.Map
+
+	channelCustomRewardsTotal typedDesc
+	channelCustomRewardCost   typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token of the
+	// broadcaster with the channel:read:redemptions scope
+	registerUserCollector("channel_custom_rewards", defaultDisabled, priorityNormal, NewChannelCustomRewardsCollector)
+}
+
+func NewChannelCustomRewardsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelCustomRewardsCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+		scopeWarned:  &sync.Map{},
+
+		channelCustomRewardsTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_custom_rewards_total"),
+			"The number of channel points custom rewards of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelCustomRewardCost: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_custom_reward_cost"),
+			"The cost in channel points of a custom reward of a channel.",
+			[]string{"username", "login", "reward"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelCustomRewardsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	scopeMissing := false
+	defer func() { setScopeMissing("channel_custom_rewards", scopeMissing) }()
+
+	for _, user := range users {
+		rewards, err := getCustomRewards(c.client, user.ID, false)
+
+		// the rewards can only be read with a token of the broadcaster
+		if errors.Is(err, ErrUnauthorized) {
+			if _, warned := c.scopeWarned.LoadOrStore(user.Login, true); !warned {
+				logger.Warn("Skipping the custom rewards of a channel, the access token is not the broadcaster's or lacks the channel:read:redemptions scope", "login", user.Login, "err", err)
+			}
+
+			scopeMissing = true
+			continue
+		}
+
+		if err != nil {
+			logger.Error("Failed to collect custom rewards stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		ch <- c.channelCustomRewardsTotal.mustNewConstMetric(float64(len(rewards)), user.DisplayName, user.Login)
+
+		for _, reward := range labeledRewards(rewards, *customRewardsMaxLabeled) {
+			ch <- c.channelCustomRewardCost.mustNewConstMetric(float64(reward.Cost), user.DisplayName, user.Login, reward.Title)
+		}
+	}
+
+	return nil
+}
+
+// labeledRewards returns the rewards exported with their cost, the most
+// expensive ones first, bounded to limit to not blow up the cardinality.
+func labeledRewards(rewards []helix.ChannelCustomReward, limit int) []helix.ChannelCustomReward {
+	sorted := make([]helix.ChannelCustomReward, len(rewards))
+	copy(sorted, rewards)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Cost != sorted[j].Cost {
+			return sorted[i].Cost > sorted[j].Cost
+		}
+
+		return sorted[i].Title < sorted[j].Title
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+// getCustomRewards returns the custom rewards of a broadcaster, from the cache
+// when they were requested recently. onlyManageable restricts them to the
+// rewards created with the client ID of the exporter, which are the only ones
+// whose redemptions can be read.
+func getCustomRewards(client *helix.Client, broadcasterID string, onlyManageable bool) ([]helix.ChannelCustomReward, error) {
+	key := broadcasterID
+	if onlyManageable {
+		key += "/manageable"
+	}
+
+	if rewards, ok := customRewardsCache.Get(key); ok {
+		return rewards.([]helix.ChannelCustomReward), nil
+	}
+
+	rewardsResp, err := client.GetCustomRewards(&helix.GetCustomRewardsParams{
+		BroadcasterID:         broadcasterID,
+		OnlyManageableRewards: onlyManageable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rewardsResp.StatusCode != 200 {
+		return nil, helixError(rewardsResp.StatusCode, rewardsResp.ErrorMessage)
+	}
+
+	customRewardsCache.Set(key, rewardsResp.Data.ChannelCustomRewards, customRewardsCacheTTL)
+
+	return rewardsResp.Data.ChannelCustomRewards, nil
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var chatMaxEmotes = kingpin.Flag("chat.max-emotes",
+	"Maximum number of distinct emotes counted per channel by the channel_emote_usage collector.").
+	Default("100").Int()
+
+var (
+	emoteUsage      = map[string]map[string]int{}
+	emoteUsageMutex = sync.Mutex{}
+)
+
+type channelEmoteUsageCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelEmoteUsageTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by
+	// default, and since emote IDs have a high cardinality
+	registerCollector("channel_emote_usage", defaultDisabled, priorityNormal, NewChannelEmoteUsageCollector)
+}
+
+func NewChannelEmoteUsageCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventsubClient.On("channel.chat.message", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelChatMessageEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel chat message event", "error", err)
+			return
+		}
+
+		emoteUsageMutex.Lock()
+		defer emoteUsageMutex.Unlock()
+
+		for _, fragment := range event.Message.Fragments {
+			if fragment.Emote == nil {
+				continue
+			}
+
+			usage, ok := emoteUsage[event.BroadcasterUserLogin]
+			if !ok {
+				usage = make(map[string]int)
+				emoteUsage[event.BroadcasterUserLogin] = usage
+			}
+
+			// emotes beyond the cap are not counted, to bound the cardinality
+			if _, ok := usage[fragment.Emote.ID]; !ok && len(usage) >= *chatMaxEmotes {
+				continue
+			}
+
+			usage[fragment.Emote.ID]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		err := eventsubClient.Subscribe("channel.chat.message", broadcasterID)
+		if err != nil {
+			logger.Error("failed to subscribe to channel chat messages", "error", err)
+		}
+	}
+
+	c := channelEmoteUsageCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelEmoteUsageTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_emote_usage_total"),
+			"The number of times an emote was used in the chat of a channel.",
+			[]string{"username", "login", "emote_id"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+func (c channelEmoteUsageCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	// the counts are copied under the lock, so the chat messages are not
+	// held up while the metrics are sent to the scrape
+	emoteUsageMutex.Lock()
+	usages := make(map[string]map[string]int, len(emoteUsage))
+	for login, usage := range emoteUsage {
+		usages[login] = maps.Clone(usage)
+	}
+	emoteUsageMutex.Unlock()
+
+	for login, usage := range usages {
+		for emoteID, count := range usage {
+			ch <- c.channelEmoteUsageTotal.mustNewConstMetric(float64(count), displayNames[login], login, emoteID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestChannelEmoteUsageUpdateUnlocked(t *testing.T) {
+	server := testutil.NewServer(testutil.DefaultFixtures)
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emoteUsageMutex.Lock()
+	emoteUsage["somechannel"] = map[string]int{"25": 2, "1902": 1}
+	emoteUsageMutex.Unlock()
+	defer func() {
+		emoteUsageMutex.Lock()
+		delete(emoteUsage, "somechannel")
+		emoteUsageMutex.Unlock()
+	}()
+
+	c := channelEmoteUsageCollector{
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		client:       client,
+		channelNames: ChannelNames{"somechannel"},
+
+		channelEmoteUsageTotal: typedDesc{prometheus.NewDesc("test_emote_usage", "Test.", []string{"username", "login", "emote_id"}, nil), prometheus.CounterValue},
+	}
+
+	assertUnlockedWhileSending(t, &emoteUsageMutex, func(ch chan<- prometheus.Metric) error {
+		return c.Update(t.Context(), ch)
+	})
+}
@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// emotesCacheTTL is how long the emotes of a channel are cached for, since they
+// rarely change.
+const emotesCacheTTL = 6 * time.Hour
+
+var emotesCache = cache.DefaultCache.Named("emote")
+
+// emoteGroup is the type and tier emotes are counted by.
+type emoteGroup struct {
+	emoteType string
+	tier      string
+}
+
+type channelEmotesCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelEmotesTotal typedDesc
+}
+
+func init() {
+	registerCollector("channel_emotes", defaultDisabled, priorityNormal, NewChannelEmotesCollector)
+}
+
+func NewChannelEmotesCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelEmotesCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelEmotesTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_emotes_total"),
+			"The number of custom emotes of a channel.",
+			[]string{"username", "login", "emote_type", "tier"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelEmotesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		emotes, err := c.getChannelEmotes(user.ID)
+		if err != nil {
+			logger.Error("Failed to collect emotes stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		counts := make(map[emoteGroup]int)
+		for _, emote := range emotes {
+			counts[emoteGroup{emoteType: emote.EmoteType, tier: emote.Tier}]++
+		}
+
+		for group, count := range counts {
+			ch <- c.channelEmotesTotal.mustNewConstMetric(float64(count), user.DisplayName, user.Login, group.emoteType, group.tier)
+		}
+	}
+
+	return nil
+}
+
+// getChannelEmotes returns the custom emotes of a broadcaster, from the cache
+// when they were requested recently.
+func (c channelEmotesCollector) getChannelEmotes(broadcasterID string) ([]helix.Emote, error) {
+	if emotes, ok := emotesCache.Get(broadcasterID); ok {
+		return emotes.([]helix.Emote), nil
+	}
+
+	emotesResp, err := c.client.GetChannelEmotes(&helix.GetChannelEmotesParams{
+		BroadcasterID: broadcasterID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if emotesResp.StatusCode != 200 {
+		return nil, helixError(emotesResp.StatusCode, emotesResp.ErrorMessage)
+	}
+
+	emotesCache.Set(broadcasterID, emotesResp.Data.Emotes, emotesCacheTTL)
+
+	return emotesResp.Data.Emotes, nil
+}
@@ -1,24 +1,100 @@
 package collector
 
 import (
-	"errors"
+	"context"
 	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/damoun/twitch_exporter/internal/eventsub"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var (
+	followerMilestoneStep = kingpin.Flag("twitch.follower-milestone-step",
+		"Export twitch_channel_follower_milestone when a channel crosses a multiple of this number of followers, 0 disables it.").
+		Default("0").Int()
+	followerMilestoneWindow = kingpin.Flag("twitch.follower-milestone-window",
+		"How long twitch_channel_follower_milestone is exported after the milestone was crossed.").
+		Default("1h").Duration()
+)
+
 type channelFollowersTotalCollector struct {
 	logger       *slog.Logger
 	client       *helix.Client
 	channelNames ChannelNames
+	previous     *previousFollowers
+	milestones   *followerMilestones
+	// scopeWarned holds the logins already warned about lacking the scope
+	// to read their followers, so it is only logged once per channel
+	scopeWarned *sync.Map
+
+	channelFollowers         typedDesc
+	channelFollowersDelta    typedDesc
+	channelFollowerMilestone typedDesc
+}
+
+// previousFollowers keeps the follower count of each channel of the previous
+// scrape, keyed by login. The counts are lost on restart.
+type previousFollowers struct {
+	mtx    sync.Mutex
+	counts map[string]int
+}
+
+// delta records the follower count of a channel and returns the change since
+// the previous scrape, and whether there was a previous scrape.
+func (p *previousFollowers) delta(login string, count int) (int, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	previous, ok := p.counts[login]
+	p.counts[login] = count
+
+	return count - previous, ok
+}
+
+// followerMilestone is the last milestone a channel crossed.
+type followerMilestone struct {
+	milestone int
+	crossedAt time.Time
+}
+
+// followerMilestones keeps the last milestone each channel crossed, keyed by
+// login.
+type followerMilestones struct {
+	mtx        sync.Mutex
+	milestones map[string]followerMilestone
+}
+
+// record records the milestone crossed by a channel going from the previous
+// to the current follower count, if any, and returns the milestone to export,
+// which is the last one crossed within --twitch.follower-milestone-window.
+func (m *followerMilestones) record(login string, previous, count, step int, now time.Time) (int, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// only the highest milestone is kept when several are crossed at once,
+	// and a channel going back and forth around a milestone only crosses it
+	// once
+	last, ok := m.milestones[login]
+	if milestone := count / step * step; count/step > previous/step && milestone > last.milestone {
+		last, ok = followerMilestone{milestone: milestone, crossedAt: now}, true
+		m.milestones[login] = last
+	}
 
-	channelFollowers typedDesc
+	if !ok || now.Sub(last.crossedAt) >= *followerMilestoneWindow {
+		return 0, false
+	}
+
+	return last.milestone, true
 }
 
 func init() {
-	registerCollector("channel_followers_total", defaultEnabled, NewChannelFollowersTotalCollector)
+	registerCollector("channel_followers_total", defaultEnabled, priorityNormal, NewChannelFollowersTotalCollector)
 }
 
 func NewChannelFollowersTotalCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
@@ -26,53 +102,134 @@ func NewChannelFollowersTotalCollector(logger *slog.Logger, client *helix.Client
 		logger:       logger,
 		client:       client,
 		channelNames: channelNames,
+		previous:     &previousFollowers{counts: make(map[string]int)},
+		milestones:   &followerMilestones{milestones: make(map[string]followerMilestone)},
+		scopeWarned:  &sync.Map{},
 
-		channelFollowers: typedDesc{prometheus.NewDesc(
+		channelFollowers: typedDesc{newDesc(
 			prometheus.BuildFQName(namespace, "", "channel_followers_total"),
 			"The number of followers of a channel.",
-			[]string{"username"}, nil,
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelFollowersDelta: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_followers_delta"),
+			"The change of the number of followers of a channel since the previous scrape, negative when it lost followers.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelFollowerMilestone: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_follower_milestone"),
+			"Is 1 when a channel crossed a multiple of --twitch.follower-milestone-step followers within --twitch.follower-milestone-window.",
+			[]string{"username", "login", "milestone"}, nil,
 		), prometheus.GaugeValue},
 	}
 
+	// the scope is checked at startup, so a token lacking it is reported
+	// before the first scrape
+	c.checkScope()
+
 	return c, nil
 }
 
-func (c channelFollowersTotalCollector) Update(ch chan<- prometheus.Metric) error {
+// checkScope warns once about each channel whose followers the access token
+// cannot read. The channels which could not be checked are warned about by
+// the first scrape instead.
+func (c channelFollowersTotalCollector) checkScope() {
+	if len(c.channelNames) == 0 {
+		return
+	}
+
+	users, err := getUsersByUsernames(c.client, c.channelNames)
+	if err != nil {
+		c.logger.Warn("Failed to check the followers scope at startup", "err", err)
+		return
+	}
+
+	scopeMissing := false
+	for _, user := range users {
+		usersFollowsResp, err := c.client.GetChannelFollows(&helix.GetChannelFollowsParams{
+			BroadcasterID: user.ID,
+			First:         1,
+		})
+
+		if err != nil {
+			c.logger.Warn("Failed to check the followers scope at startup", "err", err)
+			break
+		}
+
+		if usersFollowsResp.StatusCode == http.StatusUnauthorized || usersFollowsResp.StatusCode == http.StatusForbidden {
+			c.scopeWarned.Store(user.Login, true)
+			c.logger.Warn("Skipping the followers of a channel, the access token lacks the moderator:read:followers scope or role", "login", user.Login, "err", usersFollowsResp.ErrorMessage)
+			scopeMissing = true
+		}
+	}
+
+	setScopeMissing("channel_followers_total", scopeMissing)
+}
+
+func (c channelFollowersTotalCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
 	if len(c.channelNames) == 0 {
 		return ErrNoData
 	}
 
-	usersResp, err := c.client.GetUsers(&helix.UsersParams{
-		Logins: c.channelNames,
-	})
+	channelNames := scrapeChannels(ctx, c.channelNames)
 
+	users, err := getUsersByUsernames(c.client, channelNames)
 	if err != nil {
-		c.logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
 		return err
 	}
 
-	if usersResp.StatusCode != 200 {
-		c.logger.Error("Failed to collect users stats from Twitch helix API", "err", usersResp.ErrorMessage)
-		return errors.New(usersResp.ErrorMessage)
-	}
+	scopeMissing := false
+	defer func() { setScopeMissing("channel_followers_total", scopeMissing) }()
 
-	// todo: we can avoid this with a shared cache of username to userID that has a short TTL
-	for _, user := range usersResp.Data.Users {
+	for _, user := range users {
 		usersFollowsResp, err := c.client.GetChannelFollows(&helix.GetChannelFollowsParams{
 			BroadcasterID: user.ID,
 		})
 
 		if err != nil {
-			c.logger.Error("Failed to collect follower stats from Twitch helix API", "err", err)
+			logger.Error("Failed to collect follower stats from Twitch helix API", "err", err)
 			return err
 		}
 
+		// the followers of a channel require a token of the broadcaster or
+		// of a moderator with the moderator:read:followers scope, exporting
+		// 0 instead would be misleading
+		if usersFollowsResp.StatusCode == http.StatusUnauthorized || usersFollowsResp.StatusCode == http.StatusForbidden {
+			if _, warned := c.scopeWarned.LoadOrStore(user.Login, true); !warned {
+				logger.Warn("Skipping the followers of a channel, the access token lacks the moderator:read:followers scope or role", "login", user.Login, "err", usersFollowsResp.ErrorMessage)
+			}
+
+			scopeMissing = true
+			continue
+		}
+
 		if usersFollowsResp.StatusCode != 200 {
-			c.logger.Error("Failed to collect follower stats from Twitch helix API", "err", usersFollowsResp.ErrorMessage)
-			return errors.New(usersFollowsResp.ErrorMessage)
+			logger.Error("Failed to collect follower stats from Twitch helix API", "err", usersFollowsResp.ErrorMessage)
+			return helixError(usersFollowsResp.StatusCode, usersFollowsResp.ErrorMessage)
+		}
+
+		ch <- c.channelFollowers.mustNewConstMetric(float64(usersFollowsResp.Data.Total), user.DisplayName, user.Login)
+
+		// the delta is signed rather than clamped to zero, so unfollows are
+		// visible
+		delta, ok := c.previous.delta(user.Login, usersFollowsResp.Data.Total)
+		if !ok {
+			// the first scrape only sets the baseline, so a restart does not
+			// fire the milestone the channel is already past
+			continue
 		}
 
-		ch <- c.channelFollowers.mustNewConstMetric(float64(usersFollowsResp.Data.Total), user.DisplayName)
+		ch <- c.channelFollowersDelta.mustNewConstMetric(float64(delta), user.DisplayName, user.Login)
+
+		if *followerMilestoneStep > 0 {
+			previous := usersFollowsResp.Data.Total - delta
+			if milestone, ok := c.milestones.record(user.Login, previous, usersFollowsResp.Data.Total, *followerMilestoneStep, time.Now()); ok {
+				ch <- c.channelFollowerMilestone.mustNewConstMetric(1, user.DisplayName, user.Login, strconv.Itoa(milestone))
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestChannelFollowersTotalScopeWarnedAtStartup(t *testing.T) {
+	handler := testutil.Handler(testutil.DefaultFixtures)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/channels/followers" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"Unauthorized","status":401,"message":"Missing scope: moderator:read:followers"}`))
+	}))
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer setScopeMissing("channel_followers_total", false)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	warnings := func() int {
+		return strings.Count(logs.String(), "lacks the moderator:read:followers scope")
+	}
+
+	c, err := NewChannelFollowersTotalCollector(logger, client, nil, ChannelNames{"somechannel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := warnings(); got != 1 {
+		t.Errorf("%d warnings logged at startup, want 1", got)
+	}
+
+	scopeMissingMtx.Lock()
+	missing := scopeMissing["channel_followers_total"]
+	scopeMissingMtx.Unlock()
+	if !missing {
+		t.Error("the scope is not reported missing after startup")
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	if err := c.Update(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ch) != 0 {
+		t.Errorf("%d metrics exported for a channel without the scope, want 0", len(ch))
+	}
+
+	if got := warnings(); got != 1 {
+		t.Errorf("%d warnings logged after a scrape, want only the one of startup", got)
+	}
+}
@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type hypeTrainState struct {
+	active bool
+	level  int
+	total  int
+	// cooldownEndsAt is when the next hype train can start, it is only known
+	// once a hype train ended while the exporter was running
+	cooldownEndsAt time.Time
+}
+
+var (
+	hypeTrains      = map[string]hypeTrainState{}
+	hypeTrainsMutex = sync.Mutex{}
+)
+
+type channelHypeTrainCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelHypeTrainActive      typedDesc
+	channelHypeTrainLevel       typedDesc
+	channelHypeTrainTotalPoints typedDesc
+	channelHypeTrainCooldown    typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_hype_train", defaultDisabled, priorityNormal, NewChannelHypeTrainCollector)
+}
+
+func NewChannelHypeTrainCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	onProgress := func(eventRaw json.RawMessage) {
+		var event eventsub.HypeTrainEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal hype train event", "error", err)
+			return
+		}
+
+		hypeTrainsMutex.Lock()
+		defer hypeTrainsMutex.Unlock()
+
+		hypeTrains[event.BroadcasterUserLogin] = hypeTrainState{
+			active: true,
+			level:  event.Level,
+			total:  event.Total,
+		}
+	}
+
+	onEnd := func(eventRaw json.RawMessage) {
+		var event eventsub.HypeTrainEndEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal hype train end event", "error", err)
+			return
+		}
+
+		hypeTrainsMutex.Lock()
+		defer hypeTrainsMutex.Unlock()
+
+		// the level and total are kept so the result of the last train remains visible
+		hypeTrains[event.BroadcasterUserLogin] = hypeTrainState{
+			active:         false,
+			level:          event.Level,
+			total:          event.Total,
+			cooldownEndsAt: event.CooldownEndsAt.Time,
+		}
+	}
+
+	for _, event := range []string{"channel.hype_train.begin", "channel.hype_train.progress"} {
+		if err := eventsubClient.On(event, onProgress); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := eventsubClient.On("channel.hype_train.end", onEnd); err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		for _, event := range []string{"channel.hype_train.begin", "channel.hype_train.progress", "channel.hype_train.end"} {
+			err := eventsubClient.SubscribeWithCondition(event, "1", broadcasterID, helix.EventSubCondition{
+				BroadcasterUserID: broadcasterID,
+			})
+			if err != nil {
+				logger.Error("failed to subscribe to hype train events", "event", event, "error", err)
+			}
+		}
+	}
+
+	c := channelHypeTrainCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelHypeTrainActive: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_hype_train_active"),
+			"Is a hype train running on the channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelHypeTrainLevel: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_hype_train_level"),
+			"The level of the current or last hype train of the channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelHypeTrainTotalPoints: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_hype_train_total_points"),
+			"The total points contributed to the current or last hype train of the channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelHypeTrainCooldown: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_hype_train_cooldown_until_timestamp_seconds"),
+			"When the next hype train of the channel can start, from the end of the last hype train.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelHypeTrainCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	// the states are copied under the lock so the events are not held up by
+	// the scrape, only the channels of the collector are exported since the
+	// states are shared by every eventsub subscription
+	hypeTrainsMutex.Lock()
+	states := make(map[string]hypeTrainState, len(c.channelNames))
+	for _, n := range c.channelNames {
+		login := strings.ToLower(n)
+		if state, ok := hypeTrains[login]; ok {
+			states[login] = state
+		}
+	}
+	hypeTrainsMutex.Unlock()
+
+	for login, state := range states {
+		var active float64
+		if state.active {
+			active = 1
+		}
+
+		ch <- c.channelHypeTrainActive.mustNewConstMetric(active, displayNames[login], login)
+		ch <- c.channelHypeTrainLevel.mustNewConstMetric(float64(state.level), displayNames[login], login)
+		ch <- c.channelHypeTrainTotalPoints.mustNewConstMetric(float64(state.total), displayNames[login], login)
+
+		if !state.cooldownEndsAt.IsZero() {
+			ch <- c.channelHypeTrainCooldown.mustNewConstMetric(float64(state.cooldownEndsAt.Unix()), displayNames[login], login)
+		}
+	}
+
+	return nil
+}
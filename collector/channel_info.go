@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelInfoCacheTTL is how long the information of a channel is cached for,
+// since it changes infrequently, especially while the channel is offline.
+const channelInfoCacheTTL = time.Minute
+
+var channelInfoCache = cache.DefaultCache.Named("channel_info")
+
+type channelInfoCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelInfo         typedDesc
+	channelDelaySeconds typedDesc
+}
+
+func init() {
+	registerCollector("channel_info", defaultDisabled, priorityNormal, NewChannelInfoCollector)
+}
+
+func NewChannelInfoCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelInfoCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelInfo: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_info"),
+			"The information of a channel, whether it is live or not.",
+			[]string{"username", "login", "title", "game", "language", "delay_seconds"}, nil,
+		), prometheus.GaugeValue},
+		channelDelaySeconds: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_delay_seconds"),
+			"The stream delay configured for a channel, whether it is live or not.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelInfoCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	channels, err := getChannelInformation(c.client, users)
+	if err != nil {
+		logger.Error("Failed to collect channel stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	// the channel information has no login, look it up from the users instead
+	logins := make(map[string]string)
+	for _, user := range users {
+		logins[user.ID] = user.Login
+	}
+
+	for _, channel := range channels {
+		ch <- c.channelInfo.mustNewConstMetric(1, channel.BroadcasterName, logins[channel.BroadcasterID], channel.Title, channel.GameName, channel.BroadcasterLanguage, strconv.Itoa(channel.Delay))
+		ch <- c.channelDelaySeconds.mustNewConstMetric(float64(channel.Delay), channel.BroadcasterName, logins[channel.BroadcasterID])
+	}
+
+	return nil
+}
+
+// getChannelInformation returns the channel information of the given users.
+// The information is cached per broadcaster, and the ones which are not cached
+// are requested with a single call.
+func getChannelInformation(client *helix.Client, users []helix.User) ([]helix.ChannelInformation, error) {
+	channels := []helix.ChannelInformation{}
+	missing := []string{}
+
+	for _, user := range users {
+		if channel, ok := channelInfoCache.Get(user.ID); ok {
+			channels = append(channels, channel.(helix.ChannelInformation))
+			continue
+		}
+
+		missing = append(missing, user.ID)
+	}
+
+	if len(missing) == 0 {
+		return channels, nil
+	}
+
+	missingChannels, err := inBatches(missing, func(batch []string) ([]helix.ChannelInformation, error) {
+		channelsResp, err := client.GetChannelInformation(&helix.GetChannelInformationParams{
+			BroadcasterIDs: batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if channelsResp.StatusCode != 200 {
+			return nil, helixError(channelsResp.StatusCode, channelsResp.ErrorMessage)
+		}
+
+		return channelsResp.Data.Channels, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range missingChannels {
+		channelInfoCache.Set(channel.BroadcasterID, channel, channelInfoCacheTTL)
+		channels = append(channels, channel)
+	}
+
+	return channels, nil
+}
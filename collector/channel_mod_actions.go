@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type modActionKey struct {
+	username string
+	action   string
+}
+
+var (
+	modActions      = map[modActionKey]int{}
+	modActionsMutex = sync.Mutex{}
+)
+
+type channelModActionsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelModActionsTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_mod_actions", defaultDisabled, priorityNormal, NewChannelModActionsCollector)
+}
+
+// NewChannelModActionsCollector counts the moderator actions of a channel by
+// type. The broadcaster must have granted the moderator:read:* scopes of the
+// actions, such as moderator:read:banned_users and
+// moderator:read:chat_settings, or the channel:moderate scope.
+func NewChannelModActionsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventsubClient.On("channel.moderate", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelModerateEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel moderate event", "error", err)
+			return
+		}
+
+		modActionsMutex.Lock()
+		defer modActionsMutex.Unlock()
+
+		modActions[modActionKey{username: event.BroadcasterUserLogin, action: event.Action}]++
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		// the actions are read as the broadcaster, who moderates its channel
+		err := eventsubClient.SubscribeWithCondition("channel.moderate", "2", broadcasterID, helix.EventSubCondition{
+			BroadcasterUserID: broadcasterID,
+			ModeratorUserID:   broadcasterID,
+		})
+		if err != nil {
+			logger.Error("failed to subscribe to channel moderate events", "error", err)
+		}
+	}
+
+	c := channelModActionsCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelModActionsTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_mod_actions_total"),
+			"The number of moderator actions in a channel by action, such as delete, timeout, ban, clear, slow or emoteonly.",
+			[]string{"username", "login", "action"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+func (c channelModActionsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	modActionsMutex.Lock()
+	defer modActionsMutex.Unlock()
+
+	for key, count := range modActions {
+		ch <- c.channelModActionsTotal.mustNewConstMetric(float64(count), displayNames[key.username], key.username, key.action)
+	}
+
+	return nil
+}
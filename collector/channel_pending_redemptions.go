@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// redemptionsPage is a page of the redemptions of a custom reward, the helix
+// client does not decode the pagination of the redemptions.
+type redemptionsPage struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Pagination helix.Pagination `json:"pagination"`
+}
+
+type channelPendingRedemptionsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	clientID     string
+	channelNames ChannelNames
+	// scopeWarned holds the logins already warned about lacking the scope
+	// to read their redemptions, so it is only logged once per channel
+	scopeWarned *sync.Map
+
+	channelPendingRedemptions typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token of the
+	// broadcaster with the channel:read:redemptions scope
+	registerUserCollector("channel_pending_redemptions", defaultDisabled, priorityNormal, NewChannelPendingRedemptionsCollector)
+}
+
+func NewChannelPendingRedemptionsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	// the redemptions are paged through directly, since the helix client
+	// does not return their cursor
+	clientID, err := getHelixClientID(client)
+	if err != nil {
+		return nil, err
+	}
+
+	c := channelPendingRedemptionsCollector{
+		logger:       logger,
+		client:       client,
+		clientID:     clientID,
+		channelNames: channelNames,
+		scopeWarned:  &sync.Map{},
+
+		channelPendingRedemptions: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_pending_redemptions"),
+			"The number of unfulfilled redemptions of a custom reward of a channel.",
+			[]string{"username", "login", "reward"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelPendingRedemptionsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	scopeMissing := false
+	defer func() { setScopeMissing("channel_pending_redemptions", scopeMissing) }()
+
+	for _, user := range users {
+		// Twitch only returns the redemptions of the rewards created with
+		// the client ID of the exporter
+		rewards, err := getCustomRewards(c.client, user.ID, true)
+
+		if errors.Is(err, ErrUnauthorized) {
+			if _, warned := c.scopeWarned.LoadOrStore(user.Login, true); !warned {
+				logger.Warn("Skipping the redemptions of a channel, the access token is not the broadcaster's or lacks the channel:read:redemptions scope", "login", user.Login, "err", err)
+			}
+
+			scopeMissing = true
+			continue
+		}
+
+		if err != nil {
+			logger.Error("Failed to collect custom rewards stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		for _, reward := range rewards {
+			pending, err := c.countPendingRedemptions(user.ID, reward.ID)
+			if err != nil {
+				logger.Error("Failed to collect redemptions stats from Twitch helix API", "err", err)
+				return err
+			}
+
+			ch <- c.channelPendingRedemptions.mustNewConstMetric(float64(pending), user.DisplayName, user.Login, reward.Title)
+		}
+	}
+
+	return nil
+}
+
+// countPendingRedemptions pages through the unfulfilled redemptions of a
+// reward and returns how many there are.
+func (c channelPendingRedemptionsCollector) countPendingRedemptions(broadcasterID, rewardID string) (int, error) {
+	pending := 0
+	cursor := ""
+	for {
+		query := url.Values{
+			"broadcaster_id": {broadcasterID},
+			"reward_id":      {rewardID},
+			"status":         {"UNFULFILLED"},
+			"first":          {"50"},
+		}
+		if cursor != "" {
+			query.Set("after", cursor)
+		}
+
+		var page redemptionsPage
+		if _, err := helixGet(c.client, c.clientID, "/channel_points/custom_rewards/redemptions", query, &page); err != nil {
+			return 0, err
+		}
+
+		pending += len(page.Data)
+
+		cursor = page.Pagination.Cursor
+		if cursor == "" || len(page.Data) == 0 {
+			return pending, nil
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// polls holds the votes of each choice of the running poll of a channel
+	polls      = map[string]map[string]int{}
+	pollsMutex = sync.Mutex{}
+)
+
+type channelPollsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelPollVotesTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_polls", defaultDisabled, priorityNormal, NewChannelPollsCollector)
+}
+
+func NewChannelPollsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	onProgress := func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelPollEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel poll event", "error", err)
+			return
+		}
+
+		votes := make(map[string]int)
+		for _, choice := range event.Choices {
+			votes[choice.Title] = choice.Votes
+		}
+
+		pollsMutex.Lock()
+		defer pollsMutex.Unlock()
+
+		polls[event.BroadcasterUserLogin] = votes
+	}
+
+	onEnd := func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelPollEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel poll event", "error", err)
+			return
+		}
+
+		pollsMutex.Lock()
+		defer pollsMutex.Unlock()
+
+		delete(polls, event.BroadcasterUserLogin)
+	}
+
+	for _, event := range []string{"channel.poll.begin", "channel.poll.progress"} {
+		if err := eventsubClient.On(event, onProgress); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := eventsubClient.On("channel.poll.end", onEnd); err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		for _, event := range []string{"channel.poll.begin", "channel.poll.progress", "channel.poll.end"} {
+			err := eventsubClient.SubscribeWithCondition(event, "1", broadcasterID, helix.EventSubCondition{
+				BroadcasterUserID: broadcasterID,
+			})
+			if err != nil {
+				logger.Error("failed to subscribe to channel poll events", "event", event, "error", err)
+			}
+		}
+	}
+
+	c := channelPollsCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelPollVotesTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_poll_votes_total"),
+			"The number of votes for each choice of the running poll of a channel.",
+			[]string{"username", "login", "choice"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelPollsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	pollsMutex.Lock()
+	defer pollsMutex.Unlock()
+
+	for login, votes := range polls {
+		for choice, count := range votes {
+			ch <- c.channelPollVotesTotal.mustNewConstMetric(float64(count), displayNames[login], login, choice)
+		}
+	}
+
+	return nil
+}
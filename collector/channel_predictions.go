@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// predictions holds the channel points of each outcome of the running prediction of a channel
+	predictions      = map[string]map[string]int{}
+	predictionsMutex = sync.Mutex{}
+)
+
+type channelPredictionsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelPredictionPointsTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_predictions", defaultDisabled, priorityNormal, NewChannelPredictionsCollector)
+}
+
+func NewChannelPredictionsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	onProgress := func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelPredictionEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel prediction event", "error", err)
+			return
+		}
+
+		points := make(map[string]int)
+		for _, outcome := range event.Outcomes {
+			points[outcome.Title] = outcome.ChannelPoints
+		}
+
+		predictionsMutex.Lock()
+		defer predictionsMutex.Unlock()
+
+		predictions[event.BroadcasterUserLogin] = points
+	}
+
+	onEnd := func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelPredictionEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel prediction event", "error", err)
+			return
+		}
+
+		predictionsMutex.Lock()
+		defer predictionsMutex.Unlock()
+
+		delete(predictions, event.BroadcasterUserLogin)
+	}
+
+	for _, event := range []string{"channel.prediction.begin", "channel.prediction.progress", "channel.prediction.lock"} {
+		if err := eventsubClient.On(event, onProgress); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := eventsubClient.On("channel.prediction.end", onEnd); err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		for _, event := range []string{"channel.prediction.begin", "channel.prediction.progress", "channel.prediction.lock", "channel.prediction.end"} {
+			err := eventsubClient.SubscribeWithCondition(event, "1", broadcasterID, helix.EventSubCondition{
+				BroadcasterUserID: broadcasterID,
+			})
+			if err != nil {
+				logger.Error("failed to subscribe to channel prediction events", "event", event, "error", err)
+			}
+		}
+	}
+
+	c := channelPredictionsCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelPredictionPointsTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_prediction_points_total"),
+			"The number of channel points spent on each outcome of the running prediction of a channel.",
+			[]string{"username", "login", "outcome"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelPredictionsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	predictionsMutex.Lock()
+	defer predictionsMutex.Unlock()
+
+	for login, points := range predictions {
+		for outcome, count := range points {
+			ch <- c.channelPredictionPointsTotal.mustNewConstMetric(float64(count), displayNames[login], login, outcome)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	raidIncoming = "incoming"
+	raidOutgoing = "outgoing"
+)
+
+// raidDeliveryWindow is how long the second delivery of a raid between two
+// monitored channels is waited for.
+const raidDeliveryWindow = time.Minute
+
+var raidInfoTTL = kingpin.Flag("eventsub.raid-info-ttl",
+	"How long the last raid of a channel is exported as twitch_channel_raid_info after it happened.").
+	Default("10m").Duration()
+
+type raidKey struct {
+	username  string
+	direction string
+}
+
+type raidState struct {
+	count   int
+	viewers int
+}
+
+// raidEvent identifies a raid by its content, the eventsub messages of the
+// subscriptions delivering it have distinct IDs.
+type raidEvent struct {
+	from    string
+	to      string
+	viewers int
+}
+
+// raidInfo is a raid of a monitored channel, exported until it expires.
+type raidInfo struct {
+	from      string
+	to        string
+	viewers   int
+	expiresAt time.Time
+}
+
+var (
+	raids      = map[raidKey]raidState{}
+	lastRaids  = map[raidKey]raidInfo{} // the last raid of each channel and direction
+	raidsMutex = sync.Mutex{}
+
+	// pendingRaids holds when the raids between two monitored channels were
+	// first delivered, they are delivered again by the subscription of the
+	// other channel which must be skipped
+	pendingRaids = map[raidEvent]time.Time{}
+)
+
+type channelRaidsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelRaidsTotal  typedDesc
+	channelRaidViewers typedDesc
+	channelRaidInfo    typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_raids", defaultDisabled, priorityNormal, NewChannelRaidsCollector)
+}
+
+func NewChannelRaidsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	broadcasterIDs, err := getBroadcasterIDs(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventsubClient.On("channel.raid", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelRaidEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel raid event", "error", err)
+			return
+		}
+
+		recordRaid(channelNames, event, time.Now())
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, broadcasterID := range broadcasterIDs {
+		conditions := []helix.EventSubCondition{
+			{ToBroadcasterUserID: broadcasterID},
+			{FromBroadcasterUserID: broadcasterID},
+		}
+
+		for _, condition := range conditions {
+			err := eventsubClient.SubscribeWithCondition("channel.raid", "1", broadcasterID, condition)
+			if err != nil {
+				logger.Error("failed to subscribe to channel raids", "error", err)
+			}
+		}
+	}
+
+	c := channelRaidsCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelRaidsTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_raids_total"),
+			"The number of raids received or sent by a channel since the previous scrape.",
+			[]string{"username", "login", "direction"}, nil,
+		), prometheus.GaugeValue},
+		channelRaidViewers: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_raid_viewers"),
+			"The number of viewers carried by the last raid received or sent by a channel.",
+			[]string{"username", "login", "direction"}, nil,
+		), prometheus.GaugeValue},
+		channelRaidInfo: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_raid_info"),
+			"The last raid received or sent by a channel, exported for --eventsub.raid-info-ttl after it happened.",
+			[]string{"from", "to", "viewers"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+// recordRaid counts a raid for the monitored channels on both of its sides. A
+// raid between two monitored channels is delivered by the subscriptions of
+// both, so it is only recorded on its first delivery.
+func recordRaid(channelNames ChannelNames, event eventsub.ChannelRaidEvent, now time.Time) {
+	monitored := func(username string) bool {
+		return slices.ContainsFunc(channelNames, func(n string) bool { return strings.EqualFold(n, username) })
+	}
+
+	raidsMutex.Lock()
+	defer raidsMutex.Unlock()
+
+	for raid, deliveredAt := range pendingRaids {
+		if now.Sub(deliveredAt) > raidDeliveryWindow {
+			delete(pendingRaids, raid)
+		}
+	}
+
+	if monitored(event.ToBroadcasterUserLogin) && monitored(event.FromBroadcasterUserLogin) {
+		raid := raidEvent{from: event.FromBroadcasterUserLogin, to: event.ToBroadcasterUserLogin, viewers: event.Viewers}
+		if _, ok := pendingRaids[raid]; ok {
+			delete(pendingRaids, raid)
+			return
+		}
+		pendingRaids[raid] = now
+	}
+
+	info := raidInfo{
+		from:      event.FromBroadcasterUserLogin,
+		to:        event.ToBroadcasterUserLogin,
+		viewers:   event.Viewers,
+		expiresAt: now.Add(*raidInfoTTL),
+	}
+
+	record := func(username, direction string) {
+		if !monitored(username) {
+			return
+		}
+
+		key := raidKey{username: username, direction: direction}
+		state := raids[key]
+		state.count++
+		state.viewers = event.Viewers
+		raids[key] = state
+
+		lastRaids[key] = info
+	}
+
+	record(event.ToBroadcasterUserLogin, raidIncoming)
+	record(event.FromBroadcasterUserLogin, raidOutgoing)
+}
+
+func (c channelRaidsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	// the raids are counted since the previous scrape, like the chatters of
+	// the chat collector, so the counts are reset once they are read. they
+	// are copied under the lock so the events are not held up by the scrape
+	raidsMutex.Lock()
+	states := make(map[raidKey]raidState, len(raids))
+	for key, state := range raids {
+		states[key] = state
+		state.count = 0
+		raids[key] = state
+	}
+
+	// a raid between two monitored channels is the last raid of both, so it
+	// is only exported once
+	now := time.Now()
+	infos := map[raidInfo]bool{}
+	for key, info := range lastRaids {
+		if now.After(info.expiresAt) {
+			delete(lastRaids, key)
+			continue
+		}
+
+		infos[info] = true
+	}
+	raidsMutex.Unlock()
+
+	for key, state := range states {
+		ch <- c.channelRaidsTotal.mustNewConstMetric(float64(state.count), displayNames[key.username], key.username, key.direction)
+		ch <- c.channelRaidViewers.mustNewConstMetric(float64(state.viewers), displayNames[key.username], key.username, key.direction)
+	}
+
+	for info := range infos {
+		ch <- c.channelRaidInfo.mustNewConstMetric(1, info.from, info.to, strconv.Itoa(info.viewers))
+	}
+
+	return nil
+}
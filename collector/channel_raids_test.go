@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+)
+
+func TestRecordRaid(t *testing.T) {
+	ttl := *raidInfoTTL
+	defer func() { *raidInfoTTL = ttl }()
+	*raidInfoTTL = 10 * time.Minute
+
+	resetRaids := func() {
+		raidsMutex.Lock()
+		defer raidsMutex.Unlock()
+		clear(raids)
+		clear(lastRaids)
+		clear(pendingRaids)
+	}
+	resetRaids()
+	defer resetRaids()
+
+	channelNames := ChannelNames{"somechannel", "otherchannel"}
+	now := time.Now()
+
+	aToB := eventsub.ChannelRaidEvent{FromBroadcasterUserLogin: "somechannel", ToBroadcasterUserLogin: "otherchannel", Viewers: 42}
+	bToA := eventsub.ChannelRaidEvent{FromBroadcasterUserLogin: "otherchannel", ToBroadcasterUserLogin: "somechannel", Viewers: 7}
+	toUnmonitored := eventsub.ChannelRaidEvent{FromBroadcasterUserLogin: "somechannel", ToBroadcasterUserLogin: "unmonitored", Viewers: 3}
+
+	// the raids between the monitored channels are delivered by the
+	// subscriptions of both channels, the one to an unmonitored channel once
+	for _, event := range []eventsub.ChannelRaidEvent{aToB, aToB, bToA, bToA, toUnmonitored} {
+		recordRaid(channelNames, event, now)
+	}
+
+	raidsMutex.Lock()
+	got := maps.Clone(raids)
+	infos := map[raidInfo]bool{}
+	for _, info := range lastRaids {
+		infos[info] = true
+	}
+	pending := len(pendingRaids)
+	raidsMutex.Unlock()
+
+	want := map[raidKey]raidState{
+		{username: "somechannel", direction: raidOutgoing}:  {count: 2, viewers: 3},
+		{username: "somechannel", direction: raidIncoming}:  {count: 1, viewers: 7},
+		{username: "otherchannel", direction: raidOutgoing}: {count: 1, viewers: 7},
+		{username: "otherchannel", direction: raidIncoming}: {count: 1, viewers: 42},
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("raids = %v, want %v", got, want)
+	}
+
+	wantInfos := map[raidInfo]bool{
+		{from: "otherchannel", to: "somechannel", viewers: 7, expiresAt: now.Add(*raidInfoTTL)}:  true,
+		{from: "somechannel", to: "otherchannel", viewers: 42, expiresAt: now.Add(*raidInfoTTL)}: true,
+		{from: "somechannel", to: "unmonitored", viewers: 3, expiresAt: now.Add(*raidInfoTTL)}:   true,
+	}
+	if !maps.Equal(infos, wantInfos) {
+		t.Errorf("raid infos = %v, want %v", infos, wantInfos)
+	}
+
+	if pending != 0 {
+		t.Errorf("%d raids wait for their second delivery, want 0", pending)
+	}
+
+	// a second delivery which never came does not hide the next raid once
+	// the delivery window passed
+	recordRaid(channelNames, aToB, now)
+	recordRaid(channelNames, aToB, now.Add(raidDeliveryWindow+time.Second))
+
+	raidsMutex.Lock()
+	count := raids[raidKey{username: "otherchannel", direction: raidIncoming}].count
+	raidsMutex.Unlock()
+
+	if count != 3 {
+		t.Errorf("otherchannel received %d raids, want 3", count)
+	}
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scheduleMaxSegments is the number of upcoming segments read per channel, so
+// recurring schedules don't make us walk a year of slots.
+const scheduleMaxSegments = 20
+
+type channelScheduleCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelScheduledSegments typedDesc
+	channelNextScheduled     typedDesc
+	channelScheduleVacation  typedDesc
+}
+
+func init() {
+	registerCollector("channel_schedule", defaultDisabled, priorityNormal, NewChannelScheduleCollector)
+}
+
+func NewChannelScheduleCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelScheduleCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelScheduledSegments: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_scheduled_segments_total"),
+			"The number of upcoming scheduled streams of a channel, capped at 20.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelNextScheduled: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_next_scheduled_timestamp_seconds"),
+			"The start time of the next scheduled stream of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelScheduleVacation: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_schedule_vacation"),
+			"Is the channel schedule in vacation mode.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelScheduleCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		segments := []helix.GetScheduleSegment{}
+		vacation := false
+		cursor := ""
+
+		for len(segments) < scheduleMaxSegments {
+			scheduleResp, err := c.client.GetSchedule(&helix.GetScheduleParams{
+				BroadcasterID: user.ID,
+				First:         scheduleMaxSegments - len(segments),
+				After:         cursor,
+			})
+
+			if err != nil {
+				logger.Error("Failed to collect schedule stats from Twitch helix API", "err", err)
+				return err
+			}
+
+			// channels without a schedule are reported as not found
+			if scheduleResp.StatusCode == http.StatusNotFound {
+				break
+			}
+
+			if scheduleResp.StatusCode != 200 {
+				logger.Error("Failed to collect schedule stats from Twitch helix API", "err", scheduleResp.ErrorMessage)
+				return helixError(scheduleResp.StatusCode, scheduleResp.ErrorMessage)
+			}
+
+			vacation = !scheduleResp.Data.Schedule.Vacation.StartTime.IsZero()
+
+			for _, segment := range scheduleResp.Data.Schedule.Segments {
+				if segment.CanceledUntil != "" {
+					continue
+				}
+
+				segments = append(segments, segment)
+			}
+
+			cursor = scheduleResp.Data.Pagination.Cursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		if len(segments) > scheduleMaxSegments {
+			segments = segments[:scheduleMaxSegments]
+		}
+
+		ch <- c.channelScheduledSegments.mustNewConstMetric(float64(len(segments)), user.DisplayName, user.Login)
+
+		var vacationState float64
+		if vacation {
+			vacationState = 1
+		}
+
+		ch <- c.channelScheduleVacation.mustNewConstMetric(vacationState, user.DisplayName, user.Login)
+
+		// segments are returned in chronological order
+		if len(segments) > 0 {
+			ch <- c.channelNextScheduled.mustNewConstMetric(float64(segments[0].StartTime.Unix()), user.DisplayName, user.Login)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type channelStreamKeyCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelStreamKeyPresent typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token with the
+	// channel:read:stream_key scope of the broadcaster
+	registerUserCollector("channel_stream_key", defaultDisabled, priorityNormal, NewChannelStreamKeyCollector)
+}
+
+func NewChannelStreamKeyCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelStreamKeyCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelStreamKeyPresent: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_stream_key_present"),
+			"Whether a stream key is set for the channel. Requires the channel:read:stream_key scope.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelStreamKeyCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	scopeMissing := false
+	defer func() { setScopeMissing("channel_stream_key", scopeMissing) }()
+
+	for _, user := range users {
+		// the stream key is a secret, it is only checked for presence and
+		// never logged nor cached
+		streamKeyResp, err := c.client.GetStreamKey(&helix.StreamKeyParams{
+			BroadcasterID: user.ID,
+		})
+
+		if err != nil {
+			logger.Error("Failed to collect stream key from Twitch helix API", "err", err)
+			return err
+		}
+
+		if streamKeyResp.StatusCode != 200 {
+			err := helixError(streamKeyResp.StatusCode, streamKeyResp.ErrorMessage)
+
+			// the stream key can only be read by the broadcaster of the
+			// channel, the other channels are skipped
+			if errors.Is(err, ErrUnauthorized) {
+				scopeMissing = true
+				continue
+			}
+
+			logger.Error("Failed to collect stream key from Twitch helix API", "err", err)
+			return err
+		}
+
+		present := 0
+		for _, key := range streamKeyResp.Data.Data {
+			if key.StreamKey != "" {
+				present = 1
+			}
+		}
+
+		ch <- c.channelStreamKeyPresent.mustNewConstMetric(float64(present), user.DisplayName, user.Login)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type channelStreamMarkersTotalCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelStreamMarkersTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token with the
+	// user:read:broadcast scope of the broadcaster
+	registerUserCollector("channel_stream_markers_total", defaultDisabled, priorityNormal, NewChannelStreamMarkersTotalCollector)
+}
+
+func NewChannelStreamMarkersTotalCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelStreamMarkersTotalCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelStreamMarkersTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_stream_markers_total"),
+			"The number of markers created on the current stream of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelStreamMarkersTotalCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		markers := 0
+		cursor := ""
+
+		for {
+			markersResp, err := c.client.GetStreamMarkers(&helix.StreamMarkersParams{
+				UserID: user.ID,
+				First:  100,
+				After:  cursor,
+			})
+
+			if err != nil {
+				logger.Error("Failed to collect stream markers stats from Twitch helix API", "err", err)
+				return err
+			}
+
+			// offline channels, or channels without VODs enabled, have no
+			// current stream to read the markers of
+			if markersResp.StatusCode == http.StatusNotFound {
+				break
+			}
+
+			if markersResp.StatusCode != 200 {
+				logger.Error("Failed to collect stream markers stats from Twitch helix API", "err", markersResp.ErrorMessage)
+				return helixError(markersResp.StatusCode, markersResp.ErrorMessage)
+			}
+
+			for _, streamMarker := range markersResp.Data.StreamMarkers {
+				for _, video := range streamMarker.Videos {
+					markers += len(video.Markers)
+				}
+			}
+
+			cursor = markersResp.Data.Pagination.Cursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		if markers == 0 {
+			continue
+		}
+
+		ch <- c.channelStreamMarkersTotal.mustNewConstMetric(float64(markers), user.DisplayName, user.Login)
+	}
+
+	return nil
+}
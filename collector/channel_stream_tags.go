@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var maxStreamTags = kingpin.Flag("twitch.max-stream-tags",
+	"Maximum number of tags exported per live channel by the channel_stream_tags collector.").
+	Default("10").Int()
+
+type channelStreamTagsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelStreamTag typedDesc
+}
+
+func init() {
+	registerCollector("channel_stream_tags", defaultDisabled, priorityNormal, NewChannelStreamTagsCollector)
+}
+
+func NewChannelStreamTagsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelStreamTagsCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelStreamTag: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_stream_tag"),
+			"The tags of a live channel. If stream is offline then this is absent.",
+			[]string{"username", "login", "tag"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelStreamTagsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	streams, err := getStreams(c.client, channelNames)
+
+	if err != nil {
+		logger.Error("Failed to collect stream stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, s := range streams {
+		tags := s.Tags
+		if len(tags) > *maxStreamTags {
+			tags = tags[:*maxStreamTags]
+		}
+
+		for _, tag := range tags {
+			ch <- c.channelStreamTag.mustNewConstMetric(1, s.UserName, s.UserLogin, tag)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestChannelStreamTagsCollector(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxTags int
+		streams string
+		want    string
+	}{
+		{
+			name:    "three tags",
+			maxTags: 10,
+			streams: `{"data":[{"id":"40001","user_id":"1234","user_login":"somechannel","user_name":"SomeChannel","game_id":"509658","game_name":"Just Chatting","type":"live","title":"Hello","viewer_count":42,"started_at":"2026-01-01T00:00:00Z","language":"en","tags":["English","Chill","Cozy"]}],"pagination":{}}`,
+			want: `
+# HELP twitch_channel_stream_tag The tags of a live channel. If stream is offline then this is absent.
+# TYPE twitch_channel_stream_tag gauge
+twitch_channel_stream_tag{login="somechannel",tag="Chill",username="SomeChannel"} 1
+twitch_channel_stream_tag{login="somechannel",tag="Cozy",username="SomeChannel"} 1
+twitch_channel_stream_tag{login="somechannel",tag="English",username="SomeChannel"} 1
+`,
+		},
+		{
+			name:    "three tags over --twitch.max-stream-tags",
+			maxTags: 2,
+			streams: `{"data":[{"id":"40001","user_id":"1234","user_login":"somechannel","user_name":"SomeChannel","game_id":"509658","game_name":"Just Chatting","type":"live","title":"Hello","viewer_count":42,"started_at":"2026-01-01T00:00:00Z","language":"en","tags":["English","Chill","Cozy"]}],"pagination":{}}`,
+			want: `
+# HELP twitch_channel_stream_tag The tags of a live channel. If stream is offline then this is absent.
+# TYPE twitch_channel_stream_tag gauge
+twitch_channel_stream_tag{login="somechannel",tag="Chill",username="SomeChannel"} 1
+twitch_channel_stream_tag{login="somechannel",tag="English",username="SomeChannel"} 1
+`,
+		},
+		{
+			name:    "offline",
+			maxTags: 10,
+			streams: `{"data":[],"pagination":{}}`,
+		},
+	}
+
+	maxTags := *maxStreamTags
+	defer func() { *maxStreamTags = maxTags }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*maxStreamTags = tt.maxTags
+
+			fixtures := maps.Clone(testutil.DefaultFixtures)
+			fixtures["/streams"] = tt.streams
+
+			c := newTestCollector(t, NewChannelStreamTagsCollector, fixtures, "somechannel")
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(tt.want), "twitch_channel_stream_tag"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package collector
 
 import (
-	"errors"
+	"context"
 	"log/slog"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/damoun/twitch_exporter/internal/eventsub"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,16 +15,52 @@ const (
 	notGiftedSub = "false"
 )
 
+var (
+	subPriceTier1 = kingpin.Flag("twitch.sub-price-tier1",
+		"Price of a tier 1 subscription, used to estimate the subscriber revenue.").
+		Default("4.99").Float64()
+	subPriceTier2 = kingpin.Flag("twitch.sub-price-tier2",
+		"Price of a tier 2 subscription, used to estimate the subscriber revenue.").
+		Default("9.99").Float64()
+	subPriceTier3 = kingpin.Flag("twitch.sub-price-tier3",
+		"Price of a tier 3 subscription, used to estimate the subscriber revenue.").
+		Default("24.99").Float64()
+	subPriceCurrency = kingpin.Flag("twitch.sub-price-currency",
+		"Currency of the subscription prices, exported as the currency label of the subscriber revenue estimate.").
+		Default("USD").String()
+	subRevenueIncludeGifted = kingpin.Flag("twitch.sub-revenue-include-gifted",
+		"Include the gifted subscriptions in the subscriber revenue estimate, they are paid by the gifter rather than the subscriber.").
+		Default("false").Bool()
+)
+
+// subPrice returns the price of a subscription of the tier, or 0 for an
+// unknown tier.
+func subPrice(tier string) float64 {
+	switch tier {
+	case "1000":
+		return *subPriceTier1
+	case "2000":
+		return *subPriceTier2
+	case "3000":
+		return *subPriceTier3
+	}
+
+	return 0
+}
+
 type ChannelSubscriberTotalCollector struct {
 	logger       *slog.Logger
 	client       *helix.Client
 	channelNames ChannelNames
 
 	channelSubscribersTotal typedDesc
+	channelSubscriberPoints typedDesc
+	channelGiftedSubsRatio  typedDesc
+	channelRevenueEstimate  typedDesc
 }
 
 func init() {
-	registerCollector("channel_subscribers_total", defaultDisabled, NewChannelSubscriberTotalCollector)
+	registerUserCollector("channel_subscribers_total", defaultDisabled, priorityNormal, NewChannelSubscriberTotalCollector)
 }
 
 func NewChannelSubscriberTotalCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
@@ -32,55 +69,61 @@ func NewChannelSubscriberTotalCollector(logger *slog.Logger, client *helix.Clien
 		client:       client,
 		channelNames: channelNames,
 
-		channelSubscribersTotal: typedDesc{prometheus.NewDesc(
+		channelSubscribersTotal: typedDesc{newDesc(
 			prometheus.BuildFQName(namespace, "", "channel_subscribers_total"),
 			"The number of subscriber of a channel.",
-			[]string{"username", "tier", "gifted"}, nil,
+			[]string{"username", "login", "tier", "gifted"}, nil,
+		), prometheus.GaugeValue},
+		channelSubscriberPoints: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_subscriber_points"),
+			"The subscriber points of a channel, a tier 1 subscription is worth 1, tier 2 is worth 2 and tier 3 is worth 6.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelGiftedSubsRatio: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_gifted_subs_ratio"),
+			"The ratio of gifted subscriptions among the subscriptions of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelRevenueEstimate: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_subscriber_revenue_estimate"),
+			"An estimate of the subscriber revenue of a channel from the configured subscription prices, not the actual payout.",
+			[]string{"username", "login", "currency"}, nil,
 		), prometheus.GaugeValue},
 	}
 
 	return c, nil
 }
 
-func (c ChannelSubscriberTotalCollector) Update(ch chan<- prometheus.Metric) error {
+func (c ChannelSubscriberTotalCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
 	if len(c.channelNames) == 0 {
 		return ErrNoData
 	}
 
-	usersResp, err := c.client.GetUsers(&helix.UsersParams{
-		Logins: c.channelNames,
-	})
+	channelNames := scrapeChannels(ctx, c.channelNames)
 
+	users, err := getUsersByUsernames(c.client, channelNames)
 	if err != nil {
-		c.logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
 		return err
 	}
 
-	if usersResp.StatusCode != 200 {
-		c.logger.Error("Failed to collect users stats from Twitch helix API", "err", usersResp.ErrorMessage)
-		return errors.New(usersResp.ErrorMessage)
-	}
-
-	// todo: we can avoid this with a shared cache of username to userID that has a short TTL
-	for _, user := range usersResp.Data.Users {
-		subscribtionsResp, err := c.client.GetSubscriptions(&helix.SubscriptionsParams{
-			BroadcasterID: user.ID,
-		})
-
+	for _, user := range users {
+		subscriptions, points, err := getSubscriptions(ctx, c.client, user.ID)
 		if err != nil {
-			c.logger.Error("Failed to collect subscribers stats from Twitch helix API", "err", err)
+			logger.Error("Failed to collect subscribers stats from Twitch helix API", "err", err)
 			return err
 		}
 
-		if subscribtionsResp.StatusCode != 200 {
-			c.logger.Error("Failed to collect subscribers stats from Twitch helix API", "err", subscribtionsResp.ErrorMessage)
-			return errors.New(subscribtionsResp.ErrorMessage)
-		}
+		// the points are computed by twitch over every subscription of the
+		// channel
+		ch <- c.channelSubscriberPoints.mustNewConstMetric(float64(points), user.DisplayName, user.Login)
 
 		subCounter := make(map[string]int)
 		giftedSubCounter := make(map[string]int)
 
-		for _, subscription := range subscribtionsResp.Data.Subscriptions {
+		for _, subscription := range subscriptions {
 			if subscription.IsGift {
 				if _, ok := giftedSubCounter[subscription.Tier]; !ok {
 					giftedSubCounter[subscription.Tier] = 0
@@ -94,14 +137,74 @@ func (c ChannelSubscriberTotalCollector) Update(ch chan<- prometheus.Metric) err
 			}
 		}
 
+		gifted := 0
+		revenue := 0.0
 		for tier, counter := range giftedSubCounter {
-			ch <- c.channelSubscribersTotal.mustNewConstMetric(float64(counter), user.DisplayName, tier, giftedSub)
+			gifted += counter
+			if *subRevenueIncludeGifted {
+				revenue += float64(counter) * subPrice(tier)
+			}
+			ch <- c.channelSubscribersTotal.mustNewConstMetric(float64(counter), user.DisplayName, user.Login, tier, giftedSub)
 		}
 
+		total := gifted
 		for tier, counter := range subCounter {
-			ch <- c.channelSubscribersTotal.mustNewConstMetric(float64(counter), user.DisplayName, tier, notGiftedSub)
+			total += counter
+			revenue += float64(counter) * subPrice(tier)
+			ch <- c.channelSubscribersTotal.mustNewConstMetric(float64(counter), user.DisplayName, user.Login, tier, notGiftedSub)
 		}
+
+		ch <- c.channelRevenueEstimate.mustNewConstMetric(revenue, user.DisplayName, user.Login, *subPriceCurrency)
+
+		ch <- c.channelGiftedSubsRatio.mustNewConstMetric(giftedSubsRatio(gifted, total), user.DisplayName, user.Login)
 	}
 
 	return nil
 }
+
+// getSubscriptions returns every subscription of a broadcaster, following the
+// pagination cursor until the last page or the end of the scrape, and the
+// subscriber points of the channel.
+func getSubscriptions(ctx context.Context, client *helix.Client, broadcasterID string) ([]helix.Subscription, int, error) {
+	var subscriptions []helix.Subscription
+	points := 0
+
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+
+		subscriptionsResp, err := client.GetSubscriptions(&helix.SubscriptionsParams{
+			BroadcasterID: broadcasterID,
+			First:         100,
+			After:         cursor,
+		})
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if subscriptionsResp.StatusCode != 200 {
+			return nil, 0, helixError(subscriptionsResp.StatusCode, subscriptionsResp.ErrorMessage)
+		}
+
+		subscriptions = append(subscriptions, subscriptionsResp.Data.Subscriptions...)
+		points = subscriptionsResp.Data.Points
+
+		cursor = subscriptionsResp.Data.Pagination.Cursor
+		if cursor == "" {
+			return subscriptions, points, nil
+		}
+	}
+}
+
+// giftedSubsRatio returns the ratio of gifted subscriptions, or 0 when the
+// channel has no subscription.
+func giftedSubsRatio(gifted, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(gifted) / float64(total)
+}
@@ -0,0 +1,244 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// subscriptionsFixture returns a /subscriptions answer of somechannel with a
+// subscription per tier of the list, gifted when the tier is prefixed by "g".
+func subscriptionsFixture(points int, tiers ...string) string {
+	return subscriptionsPage("", len(tiers), points, tiers...)
+}
+
+// subscriptionsPage returns a page of the subscriptions of somechannel like
+// subscriptionsFixture, with the cursor of the next page and the total of
+// every page.
+func subscriptionsPage(cursor string, total, points int, tiers ...string) string {
+	subscriptions := make([]string, 0, len(tiers))
+	for i, tier := range tiers {
+		gift := strings.HasPrefix(tier, "g")
+		subscriptions = append(subscriptions, fmt.Sprintf(`{"broadcaster_id":"1234","broadcaster_login":"somechannel","broadcaster_name":"SomeChannel","is_gift":%t,"tier":"%s","user_id":"%d","user_login":"viewer%d","user_name":"Viewer%d"}`, gift, strings.TrimPrefix(tier, "g"), 5000+i, i, i))
+	}
+
+	return fmt.Sprintf(`{"data":[%s],"pagination":{"cursor":%q},"total":%d,"points":%d}`, strings.Join(subscriptions, ","), cursor, total, points)
+}
+
+// newPagedSubscribersCollector creates a subscribers collector requesting a
+// fake Helix API serving the pages of subscriptions by their cursor.
+func newPagedSubscribersCollector(t *testing.T, pages map[string]string) testCollector {
+	t.Helper()
+
+	handler := testutil.Handler(testutil.DefaultFixtures)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if first := r.URL.Query().Get("first"); first != "100" {
+			t.Errorf("subscriptions requested by pages of %q, want 100", first)
+		}
+
+		w.Write([]byte(pages[r.URL.Query().Get("after")]))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewChannelSubscriberTotalCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), client, nil, ChannelNames{"somechannel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testCollector{t: t, collector: c}
+}
+
+func TestChannelSubscriberTotalCollector(t *testing.T) {
+	tier1, tier2, tier3, currency, includeGifted := *subPriceTier1, *subPriceTier2, *subPriceTier3, *subPriceCurrency, *subRevenueIncludeGifted
+	defer func() {
+		*subPriceTier1, *subPriceTier2, *subPriceTier3, *subPriceCurrency, *subRevenueIncludeGifted = tier1, tier2, tier3, currency, includeGifted
+	}()
+	*subPriceTier1, *subPriceTier2, *subPriceTier3, *subPriceCurrency = 5, 10, 25, "USD"
+
+	tests := []struct {
+		name          string
+		includeGifted bool
+		names         []string
+		want          string
+	}{
+		{
+			name:  "mixed tiers",
+			names: []string{"twitch_channel_subscribers_total", "twitch_channel_subscriber_points", "twitch_channel_subscriber_revenue_estimate"},
+			want: `
+# HELP twitch_channel_subscribers_total The number of subscriber of a channel.
+# TYPE twitch_channel_subscribers_total gauge
+twitch_channel_subscribers_total{gifted="false",login="somechannel",tier="1000",username="SomeChannel"} 2
+twitch_channel_subscribers_total{gifted="false",login="somechannel",tier="2000",username="SomeChannel"} 1
+twitch_channel_subscribers_total{gifted="true",login="somechannel",tier="1000",username="SomeChannel"} 1
+twitch_channel_subscribers_total{gifted="true",login="somechannel",tier="3000",username="SomeChannel"} 1
+# HELP twitch_channel_subscriber_points The subscriber points of a channel, a tier 1 subscription is worth 1, tier 2 is worth 2 and tier 3 is worth 6.
+# TYPE twitch_channel_subscriber_points gauge
+twitch_channel_subscriber_points{login="somechannel",username="SomeChannel"} 11
+# HELP twitch_channel_subscriber_revenue_estimate An estimate of the subscriber revenue of a channel from the configured subscription prices, not the actual payout.
+# TYPE twitch_channel_subscriber_revenue_estimate gauge
+twitch_channel_subscriber_revenue_estimate{currency="USD",login="somechannel",username="SomeChannel"} 20
+`,
+		},
+		{
+			name:          "mixed tiers with the gifted revenue",
+			includeGifted: true,
+			names:         []string{"twitch_channel_subscriber_revenue_estimate"},
+			want: `
+# HELP twitch_channel_subscriber_revenue_estimate An estimate of the subscriber revenue of a channel from the configured subscription prices, not the actual payout.
+# TYPE twitch_channel_subscriber_revenue_estimate gauge
+twitch_channel_subscriber_revenue_estimate{currency="USD",login="somechannel",username="SomeChannel"} 50
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*subRevenueIncludeGifted = tt.includeGifted
+
+			fixtures := maps.Clone(testutil.DefaultFixtures)
+			fixtures["/subscriptions"] = subscriptionsFixture(11, "1000", "1000", "g1000", "2000", "g3000")
+
+			c := newTestCollector(t, NewChannelSubscriberTotalCollector, fixtures, "somechannel")
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(tt.want), tt.names...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGiftedSubsRatio(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers []string
+		want  string
+	}{
+		{name: "no subscription", want: "0"},
+		{name: "no gifted subscription", tiers: []string{"1000", "2000"}, want: "0"},
+		{name: "only gifted subscriptions", tiers: []string{"g1000", "g3000"}, want: "1"},
+		{name: "mixed", tiers: []string{"1000", "1000", "g1000", "2000", "g3000"}, want: "0.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixtures := maps.Clone(testutil.DefaultFixtures)
+			fixtures["/subscriptions"] = subscriptionsFixture(0, tt.tiers...)
+
+			c := newTestCollector(t, NewChannelSubscriberTotalCollector, fixtures, "somechannel")
+			want := `
+# HELP twitch_channel_gifted_subs_ratio The ratio of gifted subscriptions among the subscriptions of a channel.
+# TYPE twitch_channel_gifted_subs_ratio gauge
+twitch_channel_gifted_subs_ratio{login="somechannel",username="SomeChannel"} ` + tt.want + "\n"
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(want), "twitch_channel_gifted_subs_ratio"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGiftedSubsRatioPaginated(t *testing.T) {
+	// the gifted subscriptions are all on the second page
+	c := newPagedSubscribersCollector(t, map[string]string{
+		"":      subscriptionsPage("page2", 5, 0, "1000", "1000", "2000"),
+		"page2": subscriptionsPage("", 5, 0, "g1000", "g3000"),
+	})
+
+	want := `
+# HELP twitch_channel_gifted_subs_ratio The ratio of gifted subscriptions among the subscriptions of a channel.
+# TYPE twitch_channel_gifted_subs_ratio gauge
+twitch_channel_gifted_subs_ratio{login="somechannel",username="SomeChannel"} 0.4
+`
+	if err := promtestutil.CollectAndCompare(c, strings.NewReader(want), "twitch_channel_gifted_subs_ratio"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetSubscriptionsCanceled(t *testing.T) {
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		w.Write([]byte(subscriptionsPage("next", 200, 0, "1000")))
+	}))
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := getSubscriptions(ctx, client, "1234"); !errors.Is(err, context.Canceled) {
+		t.Errorf("getSubscriptions() error = %v, want %v", err, context.Canceled)
+	}
+
+	if requested != 0 {
+		t.Errorf("%d pages requested after the scrape ended, want 0", requested)
+	}
+}
+
+func TestChannelSubscriberTotalPaginated(t *testing.T) {
+	// every tier has subscriptions on both pages
+	c := newPagedSubscribersCollector(t, map[string]string{
+		"":      subscriptionsPage("page2", 7, 13, "1000", "2000", "g1000", "3000"),
+		"page2": subscriptionsPage("", 7, 13, "1000", "2000", "g1000"),
+	})
+
+	want := `
+# HELP twitch_channel_subscribers_total The number of subscriber of a channel.
+# TYPE twitch_channel_subscribers_total gauge
+twitch_channel_subscribers_total{gifted="false",login="somechannel",tier="1000",username="SomeChannel"} 2
+twitch_channel_subscribers_total{gifted="false",login="somechannel",tier="2000",username="SomeChannel"} 2
+twitch_channel_subscribers_total{gifted="false",login="somechannel",tier="3000",username="SomeChannel"} 1
+twitch_channel_subscribers_total{gifted="true",login="somechannel",tier="1000",username="SomeChannel"} 2
+# HELP twitch_channel_subscriber_points The subscriber points of a channel, a tier 1 subscription is worth 1, tier 2 is worth 2 and tier 3 is worth 6.
+# TYPE twitch_channel_subscriber_points gauge
+twitch_channel_subscriber_points{login="somechannel",username="SomeChannel"} 13
+`
+	if err := promtestutil.CollectAndCompare(c, strings.NewReader(want), "twitch_channel_subscribers_total", "twitch_channel_subscriber_points"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChannelSubscriberRevenuePaginated(t *testing.T) {
+	tier1, tier2, tier3, currency, includeGifted := *subPriceTier1, *subPriceTier2, *subPriceTier3, *subPriceCurrency, *subRevenueIncludeGifted
+	defer func() {
+		*subPriceTier1, *subPriceTier2, *subPriceTier3, *subPriceCurrency, *subRevenueIncludeGifted = tier1, tier2, tier3, currency, includeGifted
+	}()
+	*subPriceTier1, *subPriceTier2, *subPriceTier3, *subPriceCurrency, *subRevenueIncludeGifted = 5, 10, 25, "USD", false
+
+	// 2 tier 1, 2 tier 2 and 1 tier 3 subscriptions are paid across the pages
+	c := newPagedSubscribersCollector(t, map[string]string{
+		"":      subscriptionsPage("page2", 7, 13, "1000", "2000", "g1000", "3000"),
+		"page2": subscriptionsPage("", 7, 13, "1000", "2000", "g1000"),
+	})
+
+	want := `
+# HELP twitch_channel_subscriber_revenue_estimate An estimate of the subscriber revenue of a channel from the configured subscription prices, not the actual payout.
+# TYPE twitch_channel_subscriber_revenue_estimate gauge
+twitch_channel_subscriber_revenue_estimate{currency="USD",login="somechannel",username="SomeChannel"} 55
+`
+	if err := promtestutil.CollectAndCompare(c, strings.NewReader(want), "twitch_channel_subscriber_revenue_estimate"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// teamsCacheTTL is how long the teams of a channel are cached for, since team
+// membership rarely changes.
+var teamsCacheTTL = kingpin.Flag("cache.team-ttl",
+	"How long the teams of a channel are cached for.").
+	Default("24h").Duration()
+
+var teamsCache = cache.DefaultCache.Named("team")
+
+type channelTeam struct {
+	ID              string `json:"id"`
+	TeamName        string `json:"team_name"`
+	TeamDisplayName string `json:"team_display_name"`
+}
+
+type channelTeamInfoCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	clientID     string
+	channelNames ChannelNames
+
+	channelTeamInfo typedDesc
+}
+
+func init() {
+	registerCollector("channel_team_info", defaultDisabled, priorityNormal, NewChannelTeamInfoCollector)
+}
+
+func NewChannelTeamInfoCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	// the teams endpoint is not supported by the helix client, so it is
+	// requested directly with the client ID of the token
+	clientID, err := getHelixClientID(client)
+	if err != nil {
+		return nil, err
+	}
+
+	c := channelTeamInfoCollector{
+		logger:       logger,
+		client:       client,
+		clientID:     clientID,
+		channelNames: channelNames,
+
+		channelTeamInfo: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_team_info"),
+			"The teams a channel is a member of.",
+			[]string{"username", "login", "team_name", "team_id"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelTeamInfoCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		teams, err := c.getChannelTeams(user.ID)
+		if err != nil {
+			logger.Error("Failed to collect team stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		for _, team := range teams {
+			ch <- c.channelTeamInfo.mustNewConstMetric(1, user.DisplayName, user.Login, team.TeamName, team.ID)
+		}
+	}
+
+	return nil
+}
+
+// getChannelTeams returns the teams of a broadcaster, from the cache when they
+// were requested within --cache.team-ttl.
+func (c channelTeamInfoCollector) getChannelTeams(broadcasterID string) ([]channelTeam, error) {
+	if teams, ok := teamsCache.Get(broadcasterID); ok {
+		return teams.([]channelTeam), nil
+	}
+
+	var teamsResp struct {
+		Data []channelTeam `json:"data"`
+	}
+
+	status, err := helixGet(c.client, c.clientID, "/teams/channel", url.Values{"broadcaster_id": {broadcasterID}}, &teamsResp)
+
+	// channels which are not part of any team may be reported as not found
+	if status == http.StatusNotFound {
+		teamsResp.Data = []channelTeam{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	teamsCache.Set(broadcasterID, teamsResp.Data, *teamsCacheTTL)
+
+	return teamsResp.Data, nil
+}
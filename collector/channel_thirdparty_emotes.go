@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/damoun/twitch_exporter/internal/thirdparty"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var thirdpartyEmotesCache = cache.DefaultCache.Named("thirdparty_emote")
+
+type channelThirdpartyEmotesCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	thirdparty   *thirdparty.Client
+	channelNames ChannelNames
+
+	channelThirdpartyEmotesTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it requests the BetterTTV and FrankerFaceZ
+	// APIs rather than the Twitch API
+	registerCollector("channel_thirdparty_emotes", defaultDisabled, priorityNormal, NewChannelThirdpartyEmotesCollector)
+}
+
+func NewChannelThirdpartyEmotesCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelThirdpartyEmotesCollector{
+		logger:       logger,
+		client:       client,
+		thirdparty:   thirdparty.New(http.DefaultClient),
+		channelNames: channelNames,
+
+		channelThirdpartyEmotesTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_thirdparty_emotes_total"),
+			"The number of emotes of a channel on third-party emote providers.",
+			[]string{"username", "login", "provider"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelThirdpartyEmotesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		counts, err := c.getEmoteCounts(user.ID)
+		if err != nil {
+			logger.Error("Failed to collect third-party emotes stats", "err", err)
+			return err
+		}
+
+		for provider, count := range counts {
+			ch <- c.channelThirdpartyEmotesTotal.mustNewConstMetric(float64(count), user.DisplayName, user.Login, provider)
+		}
+	}
+
+	return nil
+}
+
+// getEmoteCounts returns the number of third-party emotes of a broadcaster per
+// provider, from the cache when they were requested recently.
+func (c channelThirdpartyEmotesCollector) getEmoteCounts(broadcasterID string) (map[string]int, error) {
+	if counts, ok := thirdpartyEmotesCache.Get(broadcasterID); ok {
+		return counts.(map[string]int), nil
+	}
+
+	counts, err := c.thirdparty.EmoteCounts(broadcasterID)
+	if err != nil {
+		return nil, err
+	}
+
+	thirdpartyEmotesCache.Set(broadcasterID, counts, emotesCacheTTL)
+
+	return counts, nil
+}
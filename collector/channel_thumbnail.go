@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// thumbnailSize is the size the thumbnails are requested at, the smallest
+// one Twitch serves is enough to read their age.
+const thumbnailSize = "80x45"
+
+type channelThumbnailCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	httpClient   *http.Client
+	channelNames ChannelNames
+
+	channelThumbnailAge typedDesc
+}
+
+func init() {
+	// disabled by default since it requests the thumbnails from the Twitch
+	// CDN rather than the Helix API
+	registerCollector("channel_thumbnail", defaultDisabled, priorityNormal, NewChannelThumbnailCollector)
+}
+
+func NewChannelThumbnailCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelThumbnailCollector{
+		logger: logger,
+		client: client,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		channelNames: channelNames,
+
+		channelThumbnailAge: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_thumbnail_age_seconds"),
+			"How long ago the thumbnail of the live stream of the channel was updated, a high age may mean the stream is frozen.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelThumbnailCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	streams, err := getStreams(c.client, channelNames)
+	if err != nil {
+		logger.Error("could not get streams", "err", err)
+		return err
+	}
+
+	now := time.Now()
+	for _, s := range streams {
+		modified, err := c.thumbnailModified(ctx, s.ThumbnailURL)
+		if err != nil {
+			// the thumbnail is best effort, the stream is skipped rather
+			// than failing the other ones
+			logger.Warn("Failed to read the thumbnail of the stream", "login", s.UserLogin, "err", err)
+			continue
+		}
+
+		ch <- c.channelThumbnailAge.mustNewConstMetric(now.Sub(modified).Seconds(), s.UserName, s.UserLogin)
+	}
+
+	return nil
+}
+
+// thumbnailModified returns when the thumbnail was last updated, from the
+// Last-Modified header of the CDN.
+func (c channelThumbnailCollector) thumbnailModified(ctx context.Context, thumbnailURL string) (time.Time, error) {
+	thumbnailURL = strings.NewReplacer("{width}x{height}", thumbnailSize).Replace(thumbnailURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, thumbnailURL, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return http.ParseTime(resp.Header.Get("Last-Modified"))
+}
@@ -0,0 +1,129 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type channelTokenModeratorCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	// moderated holds the logins of the channels the token owner moderates,
+	// it is nil when the token lacks the scope to list them
+	moderated map[string]bool
+
+	channelTokenIsModerator typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token with the
+	// user:read:moderated_channels scope
+	registerUserCollector("channel_token_moderator", defaultDisabled, priorityNormal, NewChannelTokenModeratorCollector)
+}
+
+func NewChannelTokenModeratorCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	valid, tokenResp, err := client.ValidateToken(client.GetUserAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, errors.New("a valid user access token is required for the moderated channels")
+	}
+
+	// the moderators rarely change, so the moderated channels are only
+	// listed at startup
+	moderated, err := getModeratedChannels(client, tokenResp.Data.UserID)
+	if errors.Is(err, ErrUnauthorized) {
+		logger.Warn("the user access token cannot list the moderated channels, it requires the user:read:moderated_channels scope", "err", err)
+		moderated = nil
+	} else if err != nil {
+		return nil, err
+	} else {
+		// the broadcaster has the moderator permissions on its own channel
+		moderated[strings.ToLower(tokenResp.Data.Login)] = true
+	}
+
+	c := channelTokenModeratorCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+		moderated:    moderated,
+
+		channelTokenIsModerator: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_token_is_moderator"),
+			"Whether the owner of the user access token is a moderator or the broadcaster of the channel, as of startup.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+// getModeratedChannels returns the logins of the channels the user moderates.
+func getModeratedChannels(client *helix.Client, userID string) (map[string]bool, error) {
+	moderated := make(map[string]bool)
+
+	cursor := ""
+	for {
+		moderatedResp, err := client.GetModeratedChannels(&helix.GetModeratedChannelsParams{
+			UserID: userID,
+			First:  100,
+			After:  cursor,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if moderatedResp.StatusCode != 200 {
+			return nil, helixError(moderatedResp.StatusCode, moderatedResp.ErrorMessage)
+		}
+
+		for _, channel := range moderatedResp.Data.ModeratedChannels {
+			moderated[channel.BroadcasterLogin] = true
+		}
+
+		cursor = moderatedResp.Data.Pagination.Cursor
+		if cursor == "" {
+			return moderated, nil
+		}
+	}
+}
+
+func (c channelTokenModeratorCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	setScopeMissing("channel_token_moderator", c.moderated == nil)
+
+	if len(c.channelNames) == 0 || c.moderated == nil {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		var moderator float64
+		if c.moderated[user.Login] {
+			moderator = 1
+		}
+
+		ch <- c.channelTokenIsModerator.mustNewConstMetric(moderator, user.DisplayName, user.Login)
+	}
+
+	return nil
+}
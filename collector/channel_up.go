@@ -1,23 +1,77 @@
 package collector
 
 import (
+	"context"
 	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/damoun/twitch_exporter/internal/eventsub"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var flapWindow = kingpin.Flag("twitch.flap-window",
+	"A channel going live again within this window after going offline is counted as a flap of its stream.").
+	Default("5m").Duration()
+
+// liveState is the state of a channel on the previous scrape, and when it was
+// last seen going offline.
+type liveState struct {
+	live        bool
+	wentOffline time.Time
+}
+
+var (
+	liveStates      = map[string]liveState{}
+	streamFlaps     = map[string]int{}
+	liveStatesMutex = sync.Mutex{}
+)
+
+// recordFlap updates the state of the channel, and counts a flap when it went
+// live again within --twitch.flap-window of going offline. The transitions are
+// only seen by the scrapes, so a drop between two scrapes is missed.
+func recordFlap(login string, live bool, now time.Time) int {
+	liveStatesMutex.Lock()
+	defer liveStatesMutex.Unlock()
+
+	previous, seen := liveStates[login]
+	state := previous
+
+	switch {
+	case seen && previous.live && !live:
+		state.wentOffline = now
+	case seen && !previous.live && live && !previous.wentOffline.IsZero() && now.Sub(previous.wentOffline) <= *flapWindow:
+		streamFlaps[login]++
+	}
+
+	state.live = live
+	liveStates[login] = state
+
+	return streamFlaps[login]
+}
+
 type channelUpCollector struct {
 	logger       *slog.Logger
 	client       *helix.Client
 	channelNames ChannelNames
 
-	channelUp typedDesc
+	channelUp          typedDesc
+	channelStreamType  typedDesc
+	channelStreamFlaps typedDesc
+}
+
+// streamTypes maps the type of a stream to the value of
+// twitch_channel_stream_type, any other type is exported as 0.
+var streamTypes = map[string]float64{
+	"live":  1,
+	"rerun": 2,
 }
 
 func init() {
-	registerCollector("channel_up", defaultEnabled, NewChannelUpCollector)
+	registerCollector("channel_up", defaultEnabled, priorityHigh, NewChannelUpCollector)
 }
 
 func NewChannelUpCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
@@ -26,44 +80,72 @@ func NewChannelUpCollector(logger *slog.Logger, client *helix.Client, eventsubCl
 		client:       client,
 		channelNames: channelNames,
 
-		channelUp: typedDesc{prometheus.NewDesc(
+		channelUp: typedDesc{newDesc(
 			prometheus.BuildFQName(namespace, "", "channel_up"),
 			"Is the channel live.",
-			[]string{"username", "game"}, nil,
+			[]string{"username", "login", "game"}, nil,
 		), prometheus.GaugeValue},
+		channelStreamType: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_stream_type"),
+			"The type of the stream of the channel: 1 for live, 2 for rerun, 0 when offline or for any other type.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelStreamFlaps: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_stream_flaps_total"),
+			"The number of times the channel went live again shortly after going offline.",
+			[]string{"username", "login"}, nil,
+		), prometheus.CounterValue},
 	}
 
 	return c, nil
 }
 
-func (c channelUpCollector) Update(ch chan<- prometheus.Metric) error {
+func (c channelUpCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
 	if len(c.channelNames) == 0 {
 		return ErrNoData
 	}
 
-	streamsResp, err := c.client.GetStreams(&helix.StreamsParams{
-		UserLogins: c.channelNames,
-		First:      len(c.channelNames),
-	})
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	streams, err := getStreams(c.client, channelNames)
 
 	if err != nil {
-		c.logger.Error("could not get streams", "err", err)
+		logger.Error("could not get streams", "err", err)
 		return err
 	}
 
-	for _, n := range c.channelNames {
+	// offline channels have no stream to read the display name from
+	displayNames, err := getDisplayNames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	now := time.Now()
+	for _, n := range channelNames {
+		// the login of a channel is the lowercase form of the configured
+		// channel name
+		login := strings.ToLower(n)
 		state := 0
 		game := ""
+		streamType := ""
 
-		for _, s := range streamsResp.Data.Streams {
-			if s.UserName == n {
+		for _, s := range streams {
+			if s.UserLogin == login {
 				state = 1
 				game = s.GameName
+				streamType = s.Type
 				break
 			}
 		}
 
-		ch <- c.channelUp.mustNewConstMetric(float64(state), n, game)
+		ch <- c.channelUp.mustNewConstMetric(float64(state), displayNames[login], login, game)
+		ch <- c.channelStreamType.mustNewConstMetric(streamTypes[streamType], displayNames[login], login)
+
+		flaps := recordFlap(login, state == 1, now)
+		ch <- c.channelStreamFlaps.mustNewConstMetric(float64(flaps), displayNames[login], login)
 	}
 
 	return nil
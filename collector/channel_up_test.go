@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestChannelUpCollector(t *testing.T) {
+	tests := []struct {
+		name    string
+		streams string
+		want    string
+	}{
+		{
+			name: "live",
+			want: `
+# HELP twitch_channel_up Is the channel live.
+# TYPE twitch_channel_up gauge
+twitch_channel_up{game="Just Chatting",login="somechannel",username="SomeChannel"} 1
+# HELP twitch_channel_stream_type The type of the stream of the channel: 1 for live, 2 for rerun, 0 when offline or for any other type.
+# TYPE twitch_channel_stream_type gauge
+twitch_channel_stream_type{login="somechannel",username="SomeChannel"} 1
+`,
+		},
+		{
+			name:    "offline",
+			streams: `{"data":[],"pagination":{}}`,
+			want: `
+# HELP twitch_channel_up Is the channel live.
+# TYPE twitch_channel_up gauge
+twitch_channel_up{game="",login="somechannel",username="SomeChannel"} 0
+# HELP twitch_channel_stream_type The type of the stream of the channel: 1 for live, 2 for rerun, 0 when offline or for any other type.
+# TYPE twitch_channel_stream_type gauge
+twitch_channel_stream_type{login="somechannel",username="SomeChannel"} 0
+`,
+		},
+		{
+			name:    "rerun",
+			streams: `{"data":[{"id":"40001","user_id":"1234","user_login":"somechannel","user_name":"SomeChannel","game_id":"509658","game_name":"Just Chatting","type":"rerun","title":"Hello","viewer_count":42,"started_at":"2026-01-01T00:00:00Z","language":"en"}],"pagination":{}}`,
+			want: `
+# HELP twitch_channel_up Is the channel live.
+# TYPE twitch_channel_up gauge
+twitch_channel_up{game="Just Chatting",login="somechannel",username="SomeChannel"} 1
+# HELP twitch_channel_stream_type The type of the stream of the channel: 1 for live, 2 for rerun, 0 when offline or for any other type.
+# TYPE twitch_channel_stream_type gauge
+twitch_channel_stream_type{login="somechannel",username="SomeChannel"} 2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixtures := maps.Clone(testutil.DefaultFixtures)
+			if tt.streams != "" {
+				fixtures["/streams"] = tt.streams
+			}
+
+			c := newTestCollector(t, NewChannelUpCollector, fixtures, "somechannel")
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(tt.want), "twitch_channel_up", "twitch_channel_stream_type"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
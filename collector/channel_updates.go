@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelUpdateState is the last seen category and title of a channel.
+type channelUpdateState struct {
+	categoryID string
+	title      string
+}
+
+var (
+	channelUpdates      = map[string]channelUpdateState{}
+	categoryChanges     = map[string]int{}
+	titleChanges        = map[string]int{}
+	channelUpdatesMutex = sync.Mutex{}
+)
+
+type channelUpdatesCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelCategoryChangesTotal typedDesc
+	channelTitleChangesTotal    typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("channel_updates", defaultDisabled, priorityNormal, NewChannelUpdatesCollector)
+}
+
+// NewChannelUpdatesCollector counts the changes of category and title of a
+// channel. The channel.update event requires no scope.
+func NewChannelUpdatesCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	users, err := getUsersByUsernames(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// the current category and title are the ones the first event is
+	// compared with
+	channels, err := getChannelInformation(client, users)
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make(map[string]string)
+	for _, user := range users {
+		logins[user.ID] = user.Login
+	}
+
+	channelUpdatesMutex.Lock()
+	for _, channel := range channels {
+		channelUpdates[logins[channel.BroadcasterID]] = channelUpdateState{categoryID: channel.GameID, title: channel.Title}
+	}
+	channelUpdatesMutex.Unlock()
+
+	err = eventsubClient.On("channel.update", func(eventRaw json.RawMessage) {
+		var event eventsub.ChannelUpdateEvent
+
+		if err := json.Unmarshal(eventRaw, &event); err != nil {
+			logger.Error("failed to unmarshal channel update event", "error", err)
+			return
+		}
+
+		channelUpdatesMutex.Lock()
+		defer channelUpdatesMutex.Unlock()
+
+		login := strings.ToLower(event.BroadcasterUserLogin)
+		if previous, ok := channelUpdates[login]; ok {
+			if previous.categoryID != event.CategoryID {
+				categoryChanges[login]++
+			}
+
+			if previous.title != event.Title {
+				titleChanges[login]++
+			}
+		}
+
+		channelUpdates[login] = channelUpdateState{categoryID: event.CategoryID, title: event.Title}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		err := eventsubClient.SubscribeWithCondition("channel.update", "2", user.ID, helix.EventSubCondition{
+			BroadcasterUserID: user.ID,
+		})
+		if err != nil {
+			logger.Error("failed to subscribe to channel updates", "error", err)
+		}
+	}
+
+	c := channelUpdatesCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelCategoryChangesTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_category_changes_total"),
+			"The number of times the category of a channel changed.",
+			[]string{"username", "login"}, nil,
+		), prometheus.CounterValue},
+		channelTitleChangesTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_title_changes_total"),
+			"The number of times the title of a channel changed.",
+			[]string{"username", "login"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+func (c channelUpdatesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	displayNames, err := getDisplayNames(c.client, c.channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	channelUpdatesMutex.Lock()
+	defer channelUpdatesMutex.Unlock()
+
+	// every channel is exported, so the changes can be rated from 0
+	for login := range channelUpdates {
+		ch <- c.channelCategoryChangesTotal.mustNewConstMetric(float64(categoryChanges[login]), displayNames[login], login)
+		ch <- c.channelTitleChangesTotal.mustNewConstMetric(float64(titleChanges[login]), displayNames[login], login)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	videosMaxPages = kingpin.Flag("twitch.videos-max-pages",
+		"Maximum number of pages of videos to read per channel.").
+		Default("10").Int()
+	// videosCacheTTL is how long the videos of a channel are cached for, since
+	// the VOD library of a channel changes slowly.
+	videosCacheTTL = kingpin.Flag("cache.video-ttl",
+		"How long the videos of a channel are cached for.").
+		Default("1h").Duration()
+)
+
+var videosCache = cache.DefaultCache.Named("video")
+
+type channelVideosCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	channelNames ChannelNames
+
+	channelVideosTotal      typedDesc
+	channelVideosViewCount  typedDesc
+	channelVODMutedSegments typedDesc
+	channelVODMutedSeconds  typedDesc
+}
+
+func init() {
+	registerCollector("channel_videos", defaultDisabled, priorityLow, NewChannelVideosCollector)
+}
+
+func NewChannelVideosCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := channelVideosCollector{
+		logger:       logger,
+		client:       client,
+		channelNames: channelNames,
+
+		channelVideosTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_videos_total"),
+			"The number of videos of a channel.",
+			[]string{"username", "login", "type"}, nil,
+		), prometheus.GaugeValue},
+		channelVideosViewCount: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_videos_view_count"),
+			"The sum of the views of the videos of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelVODMutedSegments: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_vod_muted_segments_total"),
+			"The number of muted audio segments of the videos of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+		channelVODMutedSeconds: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_vod_muted_seconds_total"),
+			"The duration of the muted audio segments of the videos of a channel.",
+			[]string{"username", "login"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c channelVideosCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(c.channelNames) == 0 {
+		return ErrNoData
+	}
+
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	users, err := getUsersByUsernames(c.client, channelNames)
+	if err != nil {
+		logger.Error("Failed to collect users stats from Twitch helix API", "err", err)
+		return err
+	}
+
+	for _, user := range users {
+		videos, err := getArchiveVideos(ctx, c.client, logger, user.ID)
+		if err != nil {
+			logger.Error("Failed to collect videos stats from Twitch helix API", "err", err)
+			return err
+		}
+
+		videosByType := make(map[string]int)
+		viewCount := 0
+		mutedSegments := 0
+		mutedSeconds := 0
+
+		for _, video := range videos {
+			videosByType[video.Type]++
+			viewCount += video.ViewCount
+
+			// audio is muted when it was flagged for copyrighted content
+			for _, segment := range video.MutedSegments {
+				mutedSegments++
+				mutedSeconds += segment.Duration
+			}
+		}
+
+		for videoType, count := range videosByType {
+			ch <- c.channelVideosTotal.mustNewConstMetric(float64(count), user.DisplayName, user.Login, videoType)
+		}
+
+		ch <- c.channelVideosViewCount.mustNewConstMetric(float64(viewCount), user.DisplayName, user.Login)
+		ch <- c.channelVODMutedSegments.mustNewConstMetric(float64(mutedSegments), user.DisplayName, user.Login)
+		ch <- c.channelVODMutedSeconds.mustNewConstMetric(float64(mutedSeconds), user.DisplayName, user.Login)
+	}
+
+	return nil
+}
+
+// getArchiveVideos returns the archived streams of a broadcaster, reading at
+// most --twitch.videos-max-pages pages. Results are cached for --cache.video-ttl.
+// The walk stops when the scrape gives up on the collector.
+func getArchiveVideos(ctx context.Context, client *helix.Client, logger *slog.Logger, broadcasterID string) ([]helix.Video, error) {
+	if videos, ok := videosCache.Get(broadcasterID); ok {
+		return videos.([]helix.Video), nil
+	}
+
+	videos := []helix.Video{}
+	cursor := ""
+
+	for page := 1; ; page++ {
+		// the scrape gave up on the collector, such as when it timed out
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		videosResp, err := client.GetVideos(&helix.VideosParams{
+			UserID: broadcasterID,
+			Type:   "archive",
+			First:  100,
+			After:  cursor,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if videosResp.StatusCode != 200 {
+			return nil, helixError(videosResp.StatusCode, videosResp.ErrorMessage)
+		}
+
+		videos = append(videos, videosResp.Data.Videos...)
+		cursor = videosResp.Data.Pagination.Cursor
+
+		if cursor == "" {
+			break
+		}
+
+		if page >= *videosMaxPages {
+			logger.Warn("videos page limit reached, returning partial videos", "broadcaster_id", broadcasterID, "pages", page, "count", len(videos))
+			break
+		}
+	}
+
+	videosCache.Set(broadcasterID, videos, *videosCacheTTL)
+
+	return videos, nil
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+)
+
+func TestGetArchiveVideosCanceled(t *testing.T) {
+	maxPages := *videosMaxPages
+	defer func() { *videosMaxPages = maxPages }()
+	*videosMaxPages = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the scrape gives up on the collector while the first page is served
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		cancel()
+		w.Write([]byte(`{"data":[{"id":"1","user_id":"videos-canceled","type":"archive","view_count":10}],"pagination":{"cursor":"next"}}`))
+	}))
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := getArchiveVideos(ctx, client, logger, "videos-canceled"); !errors.Is(err, context.Canceled) {
+		t.Errorf("getArchiveVideos() error = %v, want %v", err, context.Canceled)
+	}
+
+	if requested != 1 {
+		t.Errorf("%d pages requested, want 1", requested)
+	}
+
+	if _, ok := videosCache.Get("videos-canceled"); ok {
+		t.Error("the partial videos of a canceled walk were cached")
+	}
+}
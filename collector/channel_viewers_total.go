@@ -1,23 +1,97 @@
 package collector
 
 import (
+	"context"
 	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/damoun/twitch_exporter/internal/eventsub"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var viewerAvgWindow = kingpin.Flag("twitch.viewer-avg-window",
+	"Window the average viewers of a channel are computed over, from the viewer counts of the scrapes within it.").
+	Default("10m").Duration()
+
 type ChannelViewersTotalCollector struct {
 	logger       *slog.Logger
 	client       *helix.Client
 	channelNames ChannelNames
+	viewers      *viewerSamples
+	peaks        *viewerPeaks
 
 	channelViewersTotal typedDesc
+	channelViewersAvg   typedDesc
+	channelViewersPeak  typedDesc
+}
+
+type viewerSample struct {
+	at      time.Time
+	viewers int
+}
+
+// viewerSamples keeps the viewer counts of each channel within the average
+// window, keyed by login. The samples are lost on restart.
+type viewerSamples struct {
+	mtx     sync.Mutex
+	samples map[string][]viewerSample
+}
+
+// add records the viewer count of a channel, drops the samples which fell out
+// of the window and returns the average of the remaining ones.
+func (v *viewerSamples) add(login string, viewers int, at time.Time) float64 {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	samples := append(v.samples[login], viewerSample{at: at, viewers: viewers})
+	for len(samples) > 1 && at.Sub(samples[0].at) > *viewerAvgWindow {
+		samples = samples[1:]
+	}
+	v.samples[login] = samples
+
+	total := 0
+	for _, s := range samples {
+		total += s.viewers
+	}
+
+	return float64(total) / float64(len(samples))
+}
+
+type viewerPeak struct {
+	startedAt time.Time
+	viewers   int
+}
+
+// viewerPeaks keeps the peak viewer count of the current stream of each
+// channel, keyed by login.
+type viewerPeaks struct {
+	mtx   sync.Mutex
+	peaks map[string]viewerPeak
+}
+
+// add records the viewer count of the stream of a channel and returns the peak
+// viewer count of the stream. The peak is reset when the stream started at a
+// different time, as it is a new stream.
+func (v *viewerPeaks) add(login string, viewers int, startedAt time.Time) int {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	peak, ok := v.peaks[login]
+	if !ok || !peak.startedAt.Equal(startedAt) {
+		peak = viewerPeak{startedAt: startedAt}
+	}
+
+	peak.viewers = max(peak.viewers, viewers)
+	v.peaks[login] = peak
+
+	return peak.viewers
 }
 
 func init() {
-	registerCollector("channel_viewers_total", defaultEnabled, NewChannelViewersTotalCollector)
+	registerCollector("channel_viewers_total", defaultEnabled, priorityHigh, NewChannelViewersTotalCollector)
 }
 
 func NewChannelViewersTotalCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
@@ -25,34 +99,50 @@ func NewChannelViewersTotalCollector(logger *slog.Logger, client *helix.Client,
 		logger:       logger,
 		client:       client,
 		channelNames: channelNames,
+		viewers:      &viewerSamples{samples: make(map[string][]viewerSample)},
+		peaks:        &viewerPeaks{peaks: make(map[string]viewerPeak)},
 
-		channelViewersTotal: typedDesc{prometheus.NewDesc(
+		channelViewersTotal: typedDesc{newDesc(
 			prometheus.BuildFQName(namespace, "", "channel_viewers_total"),
 			"How many viewers on this live channel. If stream is offline then this is absent.",
-			[]string{"username", "game"}, nil,
+			[]string{"username", "login", "game"}, nil,
+		), prometheus.GaugeValue},
+		channelViewersAvg: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_viewers_avg"),
+			"The average number of viewers of a live channel over the window, from the viewer counts of the scrapes within it.",
+			[]string{"username", "login", "window"}, nil,
+		), prometheus.GaugeValue},
+		channelViewersPeak: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_viewers_peak"),
+			"The highest number of viewers of the current stream of a live channel, as seen by the scrapes.",
+			[]string{"username", "login"}, nil,
 		), prometheus.GaugeValue},
 	}
 
 	return c, nil
 }
 
-func (c ChannelViewersTotalCollector) Update(ch chan<- prometheus.Metric) error {
+func (c ChannelViewersTotalCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
 	if len(c.channelNames) == 0 {
 		return ErrNoData
 	}
 
-	streamsResp, err := c.client.GetStreams(&helix.StreamsParams{
-		UserLogins: c.channelNames,
-		First:      len(c.channelNames),
-	})
+	channelNames := scrapeChannels(ctx, c.channelNames)
+
+	streams, err := getStreams(c.client, channelNames)
 
 	if err != nil {
-		c.logger.Error("could not get streams", "err", err)
+		logger.Error("could not get streams", "err", err)
 		return err
 	}
 
-	for _, s := range streamsResp.Data.Streams {
-		ch <- c.channelViewersTotal.mustNewConstMetric(float64(s.ViewerCount), s.UserName, s.GameName)
+	now := time.Now()
+	for _, s := range streams {
+		ch <- c.channelViewersTotal.mustNewConstMetric(float64(s.ViewerCount), s.UserName, s.UserLogin, s.GameName)
+		ch <- c.channelViewersAvg.mustNewConstMetric(c.viewers.add(s.UserLogin, s.ViewerCount, now), s.UserName, s.UserLogin, viewerAvgWindow.String())
+		ch <- c.channelViewersPeak.mustNewConstMetric(float64(c.peaks.add(s.UserLogin, s.ViewerCount, s.StartedAt)), s.UserName, s.UserLogin)
 	}
 
 	return nil
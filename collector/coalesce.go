@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+func helixCoalescedRequestsDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "helix", "coalesced_requests_total"),
+		"Number of Helix API requests which were not sent since an identical request was in flight.",
+		[]string{"endpoint"},
+		nil,
+	)
+}
+
+var (
+	helixCoalescedRequestsMtx = sync.Mutex{}
+	helixCoalescedRequests    = make(map[string]int)
+)
+
+// CoalescingClient is an HTTP client for the Helix API which sends identical
+// GET requests made concurrently only once, sharing the response between the
+// callers. Collectors run concurrently and often request the same streams or
+// users within a scrape.
+//
+// The requests are coalesced across the whole process rather than per scrape:
+// the helix clients are shared by the scrapes and build their requests with
+// the context they were created with, so a request does not carry the ID of
+// its scrape. Only requests in flight at the same time are coalesced, so a
+// caller never gets a response received before it made its request, even
+// when the scrapes of two Prometheus servers overlap.
+type CoalescingClient struct {
+	next  helix.HTTPClient
+	group singleflight.Group
+}
+
+// coalescedResponse is a response with its body read, so it can be returned
+// to every caller.
+type coalescedResponse struct {
+	resp *http.Response
+	body []byte
+}
+
+// NewCoalescingClient creates a CoalescingClient sending the requests with
+// next.
+func NewCoalescingClient(next helix.HTTPClient) *CoalescingClient {
+	return &CoalescingClient{next: next}
+}
+
+// Do sends the request, or waits for the response of an identical request in
+// flight. Requests are identical when they have the same URL and token.
+func (c *CoalescingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.next.Do(req)
+	}
+
+	sent := false
+	key := req.URL.String() + " " + req.Header.Get("Authorization")
+
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		sent = true
+
+		resp, err := c.next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return coalescedResponse{resp: resp, body: body}, nil
+	})
+
+	if !sent {
+		helixCoalescedRequestsMtx.Lock()
+		helixCoalescedRequests[helixEndpoint(req.URL)]++
+		helixCoalescedRequestsMtx.Unlock()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// every caller gets its own copy, since the body is consumed and the
+	// headers may be read concurrently
+	coalesced := v.(coalescedResponse)
+	resp := *coalesced.resp
+	resp.Header = coalesced.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(coalesced.body))
+
+	return &resp, nil
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescingClient(t *testing.T) {
+	hits := atomic.Int32{}
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewCoalescingClient(server.Client())
+	get := func() (string, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/streams?user_login=somechannel", nil)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	const callers = 5
+	bodies := make([]string, callers)
+	wg := sync.WaitGroup{}
+	wg.Add(callers)
+	for i := range callers {
+		go func() {
+			defer wg.Done()
+
+			var err error
+			if bodies[i], err = get(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// the first request is held in flight by the server while the
+		// others are made
+		if i == 0 {
+			<-started
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("API requested %d times, want 1", n)
+	}
+
+	for i, body := range bodies {
+		if body != `{"data":[]}` {
+			t.Errorf("caller %d got body %q", i, body)
+		}
+	}
+
+	// a request made once the previous one returned is sent again
+	if _, err := get(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := hits.Load(); n != 2 {
+		t.Errorf("API requested %d times, want 2", n)
+	}
+}
@@ -4,34 +4,114 @@
 package collector
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/cache"
 	"github.com/damoun/twitch_exporter/internal/eventsub"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/version"
 )
 
-const namespace = "twitch"
+// namespace prefixes the name of every metric, it is set with SetNamespace
+// before the exporter is created.
+var namespace = "twitch"
 
-var (
-	scrapeDurationDesc = prometheus.NewDesc(
+// SetNamespace sets the namespace prefixing the name of every metric, so
+// several exporters can be told apart. It must be called before the exporter
+// is created.
+func SetNamespace(ns string) error {
+	ns = strings.TrimSuffix(ns, "_")
+	if !model.IsValidLegacyMetricName(ns) {
+		return fmt.Errorf("invalid metric namespace: %q", ns)
+	}
+
+	namespace = ns
+
+	return nil
+}
+
+// Namespace returns the namespace prefixing the name of every metric.
+func Namespace() string {
+	return namespace
+}
+
+// The descriptions of the metrics of the exporter itself are built on use, as
+// the namespace is only known once the flags are parsed.
+
+func scrapeDurationDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
 		"node_exporter: Duration of a collector scrape.",
 		[]string{"collector"},
 		nil,
 	)
-	scrapeSuccessDesc = prometheus.NewDesc(
+}
+
+func scrapeSuccessDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
 		"node_exporter: Whether a collector succeeded.",
 		[]string{"collector"},
 		nil,
 	)
-)
+}
+
+func buildInfoDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "build_info"),
+		"The version of the exporter, with a constant value of 1.",
+		[]string{"version", "revision", "goversion"},
+		nil,
+	)
+}
+
+func lastScrapeErrorDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "last_scrape_error"),
+		"Whether a collector failed during the last scrape.",
+		nil,
+		nil,
+	)
+}
+
+func cacheHitsDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "hits_total"),
+		"Number of lookups served from the cache.",
+		[]string{"cache"},
+		nil,
+	)
+}
+
+func cacheMissesDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "misses_total"),
+		"Number of lookups not found in the cache.",
+		[]string{"cache"},
+		nil,
+	)
+}
+
+func scopeMissingDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "collector", "scope_missing"),
+		"Whether a collector skipped channels since the access token lacks the scope or role to read them.",
+		[]string{"collector"},
+		nil,
+	)
+}
 
 const (
 	defaultEnabled  = true
@@ -44,9 +124,22 @@ var (
 	initiatedCollectors    = make(map[string]Collector)
 	collectorState         = make(map[string]*bool)
 	forcedCollectors       = map[string]bool{} // collectors which have been explicitly enabled or disabled
+	userTokenCollectors    = map[string]bool{} // collectors which require a user access token
+
+	scopeMissingMtx = sync.Mutex{}
+	scopeMissing    = map[string]bool{} // collectors which lacked a scope on their last update
 )
 
-func registerCollector(collector string, isDefaultEnabled bool, factory func(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error)) {
+// setScopeMissing records whether the collector had to skip channels on its
+// last update because the access token lacks the scope or role to read them.
+func setScopeMissing(collector string, missing bool) {
+	scopeMissingMtx.Lock()
+	defer scopeMissingMtx.Unlock()
+
+	scopeMissing[collector] = missing
+}
+
+func registerCollector(collector string, isDefaultEnabled bool, priority int, factory func(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error)) {
 	var helpDefaultState string
 	if isDefaultEnabled {
 		helpDefaultState = "enabled"
@@ -60,21 +153,68 @@ func registerCollector(collector string, isDefaultEnabled bool, factory func(log
 
 	flag := kingpin.Flag(flagName, flagHelp).Default(defaultValue).Action(collectorFlagAction(collector)).Bool()
 	collectorState[collector] = flag
+	collectorPriorities[collector] = priority
+	registerCollectorTimeout(collector)
 
 	factories[collector] = factory
 }
 
+// registerUserCollector registers a collector which requests private data of
+// the broadcaster, and so requires a user access token rather than an app
+// access token.
+func registerUserCollector(collector string, isDefaultEnabled bool, priority int, factory func(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error)) {
+	registerCollector(collector, isDefaultEnabled, priority, factory)
+	userTokenCollectors[collector] = true
+}
+
+// Clients holds a helix client for each type of access token. A client is nil
+// when its access token is not configured.
+type Clients struct {
+	App  *helix.Client
+	User *helix.Client
+}
+
+// forCollector returns the client the collector should be built with, or nil
+// when the access token it requires is not configured. Collectors which do not
+// require a user access token prefer the app access token.
+func (c Clients) forCollector(collector string) *helix.Client {
+	if userTokenCollectors[collector] {
+		return c.User
+	}
+
+	if c.App != nil {
+		return c.App
+	}
+
+	return c.User
+}
+
 type Exporter struct {
-	Collectors map[string]Collector
-	client     *helix.Client
-	logger     *slog.Logger
+	Collectors   map[string]Collector
+	clients      Clients
+	channelNames ChannelNames
+	logger       *slog.Logger
+	rotation     *rotation
+
+	scrapeDuration prometheus.Summary
 }
 
 // Describe describes all the metrics ever exported by the Twitch exporter. It
 // implements prometheus.Collector.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- scrapeDurationDesc
-	ch <- scrapeSuccessDesc
+	ch <- scrapeDurationDesc()
+	ch <- scrapeSuccessDesc()
+	ch <- cacheHitsDesc()
+	ch <- cacheMissesDesc()
+	ch <- scopeMissingDesc()
+	ch <- buildInfoDesc()
+	ch <- lastScrapeErrorDesc()
+	e.scrapeDuration.Describe(ch)
+	ch <- seriesTruncatedDesc()
+	ch <- collectorTimeoutsDesc()
+	ch <- helixRetriesDesc()
+	ch <- helixCoalescedRequestsDesc()
+	ch <- helixInflightRequestsDesc()
 }
 
 func DisableDefaultCollectors() {
@@ -97,7 +237,7 @@ func collectorFlagAction(collector string) func(ctx *kingpin.ParseContext) error
 	}
 }
 
-func NewExporter(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames, filters ...string) (*Exporter, error) {
+func NewExporter(logger *slog.Logger, clients Clients, eventsubClient *eventsub.Client, channelNames ChannelNames, filters ...string) (*Exporter, error) {
 	f := make(map[string]bool)
 	for _, filter := range filters {
 		enabled, exist := collectorState[filter]
@@ -121,6 +261,12 @@ func NewExporter(logger *slog.Logger, client *helix.Client, eventsubClient *even
 		if collector, ok := initiatedCollectors[key]; ok {
 			collectors[key] = collector
 		} else {
+			client := clients.forCollector(key)
+			if client == nil {
+				logger.Warn("skipping collector, it requires a user access token", "collector", key)
+				continue
+			}
+
 			collector, err := factories[key](logger, client, eventsubClient, channelNames)
 			if err != nil {
 				return nil, err
@@ -137,26 +283,189 @@ func NewExporter(logger *slog.Logger, client *helix.Client, eventsubClient *even
 	return &Exporter{
 		Collectors: collectors,
 
-		client: client,
-		logger: logger,
+		clients:      clients,
+		channelNames: channelNames,
+		logger:       logger,
+		rotation:     newRotation(channelNames),
+
+		scrapeDuration: prometheus.NewSummary(prometheus.SummaryOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "scrape_duration_seconds",
+			Help:      "Duration of the scrapes of the exporter.",
+		}),
 	}, nil
 }
 
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	// every scrape gets an ID, which is added to the log lines of the
+	// collectors so they can be correlated
+	ctx := context.WithValue(context.Background(), scrapeIDKey{}, newScrapeID())
+	ctx = context.WithValue(ctx, seriesLimiterKey{}, newSeriesLimiter())
+
+	begin := time.Now()
+	failed := atomic.Bool{}
+
+	var run func(name string, c Collector)
+	if !e.rotation.enabled() {
+		run = func(name string, c Collector) {
+			if execute(ctx, name, c, ch, e.logger) {
+				recordScrapeSuccess(name, e.channelNames, time.Now())
+			} else {
+				failed.Store(true)
+			}
+		}
+	} else {
+		// only a batch of the channels is refreshed, the metrics of the others
+		// are the ones of the scrape which last refreshed them
+		batch := e.rotation.next()
+		ctx = context.WithValue(ctx, scrapeChannelsKey{}, batch)
+
+		run = func(name string, c Collector) {
+			metrics := make(chan prometheus.Metric)
+			go func() {
+				if execute(ctx, name, c, metrics, e.logger) {
+					recordScrapeSuccess(name, batch, time.Now())
+				} else {
+					failed.Store(true)
+				}
+				close(metrics)
+			}()
+
+			e.rotation.forward(name, batch, metrics, ch)
+		}
+	}
+
+	// the live channels are looked up by the first live only collector, at
+	// the same time as channel_up requests the same streams, so the
+	// requests are coalesced when they overlap
+	if *liveOnly {
+		ctx = withLiveChannels(ctx, e.clients.App, e.logger, scrapeChannels(ctx, e.channelNames))
+	}
+
+	// the collectors of the high and normal priorities run at once, while
+	// the low priority ones walking pages of results wait for them, so they
+	// only spend what is left of the rate limit. the rate limit is checked
+	// at the start of the scrape and again before the low priority, when it
+	// is almost exhausted the collectors below the highest priority are
+	// skipped so the cheap core collectors are not starved by the expensive
+	// ones
+	tiers := collectorTiers(e.Collectors)
+	exhausted := rateLimitExhausted()
+
 	wg := sync.WaitGroup{}
-	wg.Add(len(e.Collectors))
-	for name, c := range e.Collectors {
+	passive := map[string]Collector{}
+	for i, tier := range tiers {
+		if tier.priority == priorityPassive {
+			passive = tier.collectors
+			continue
+		}
+
+		if tier.priority < priorityNormal {
+			wg.Wait()
+			exhausted = rateLimitExhausted()
+		}
+
+		skip := i > 0 && exhausted
+		if skip {
+			scrapeLogger(ctx, e.logger).Warn("rate limit almost exhausted, skipping lower priority collectors", "priority", tier.priority, "collectors", len(tier.collectors))
+		}
+
+		for name, c := range tier.collectors {
+			if skip {
+				c = skippedCollector{}
+			} else if *liveOnly && liveOnlyCollectors[name] {
+				c = liveOnlyCollector{c}
+			}
+
+			wg.Add(1)
+			go func(name string, c Collector) {
+				run(name, c)
+				wg.Done()
+			}(name, c)
+		}
+	}
+	wg.Wait()
+
+	// the passive collectors make no request, they run once the others are
+	// done to see the responses of the scrape
+	wg.Add(len(passive))
+	for name, c := range passive {
 		go func(name string, c Collector) {
-			execute(name, c, ch, e.logger)
+			run(name, c)
 			wg.Done()
 		}(name, c)
 	}
 	wg.Wait()
+
+	e.scrapeDuration.Observe(time.Since(begin).Seconds())
+	e.scrapeDuration.Collect(ch)
+
+	lastScrapeError := 0.0
+	if failed.Load() {
+		lastScrapeError = 1
+	}
+	ch <- prometheus.MustNewConstMetric(lastScrapeErrorDesc(), prometheus.GaugeValue, lastScrapeError)
+	ch <- prometheus.MustNewConstMetric(buildInfoDesc(), prometheus.GaugeValue, 1, version.Version, version.Revision, version.GoVersion)
+
+	// the cache stats are read after the collectors ran, so they include the
+	// lookups of this scrape
+	hitsDesc, missesDesc := cacheHitsDesc(), cacheMissesDesc()
+	for name, stats := range cache.DefaultCache.Stats() {
+		ch <- prometheus.MustNewConstMetric(hitsDesc, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(missesDesc, prometheus.CounterValue, float64(stats.Misses), name)
+	}
+
+	missingDesc := scopeMissingDesc()
+	scopeMissingMtx.Lock()
+	for name := range e.Collectors {
+		missing := 0.0
+		if scopeMissing[name] {
+			missing = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(missingDesc, prometheus.GaugeValue, missing, name)
+	}
+	scopeMissingMtx.Unlock()
+
+	retriesDesc := helixRetriesDesc()
+	helixRetriesMtx.Lock()
+	for endpoint, retries := range helixRetries {
+		ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(retries), endpoint)
+	}
+	helixRetriesMtx.Unlock()
+
+	truncatedDesc := seriesTruncatedDesc()
+	seriesTruncatedMtx.Lock()
+	for collector, series := range seriesTruncated {
+		ch <- prometheus.MustNewConstMetric(truncatedDesc, prometheus.CounterValue, float64(series), collector)
+	}
+	seriesTruncatedMtx.Unlock()
+
+	timeoutsDesc := collectorTimeoutsDesc()
+	collectorTimeoutsTotalMtx.Lock()
+	for collector, timeouts := range collectorTimeoutsTotal {
+		ch <- prometheus.MustNewConstMetric(timeoutsDesc, prometheus.CounterValue, float64(timeouts), collector)
+	}
+	collectorTimeoutsTotalMtx.Unlock()
+
+	coalescedDesc := helixCoalescedRequestsDesc()
+	helixCoalescedRequestsMtx.Lock()
+	for endpoint, requests := range helixCoalescedRequests {
+		ch <- prometheus.MustNewConstMetric(coalescedDesc, prometheus.CounterValue, float64(requests), endpoint)
+	}
+	helixCoalescedRequestsMtx.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(helixInflightRequestsDesc(), prometheus.GaugeValue, float64(helixInflightRequests.Load()))
 }
 
-func execute(name string, c Collector, ch chan<- prometheus.Metric, logger *slog.Logger) {
+// execute runs the update of a collector, exporting its duration and success,
+// and returns whether it succeeded.
+func execute(ctx context.Context, name string, c Collector, ch chan<- prometheus.Metric, logger *slog.Logger) bool {
+	logger = scrapeLogger(ctx, logger)
+
 	begin := time.Now()
-	err := c.Update(ch)
+	err := update(ctx, logger, name, c, ch)
 	duration := time.Since(begin)
 	var success float64
 
@@ -172,14 +481,95 @@ func execute(name string, c Collector, ch chan<- prometheus.Metric, logger *slog
 		success = 1
 	}
 
-	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
-	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc(), prometheus.GaugeValue, duration.Seconds(), name)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc(), prometheus.GaugeValue, success, name)
+
+	return err == nil
+}
+
+// update runs the update of a collector, through the series limiter of the
+// scrape and within the timeout of the collector. A collector which times out
+// returns ErrNoData.
+func update(ctx context.Context, logger *slog.Logger, name string, c Collector, ch chan<- prometheus.Metric) error {
+	timeout := collectorTimeout(name)
+	limiter := scrapeSeriesLimiter(ctx)
+
+	if timeout <= 0 && limiter == nil {
+		return c.Update(ctx, ch)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	metrics := make(chan prometheus.Metric)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Update(ctx, metrics)
+		close(metrics)
+	}()
+
+	dropped := 0
+	for {
+		select {
+		case m, ok := <-metrics:
+			if !ok {
+				if dropped > 0 {
+					limiter.truncated(logger, name, dropped)
+				}
+
+				return <-errc
+			}
+
+			if limiter != nil && !limiter.allow() {
+				dropped++
+				continue
+			}
+
+			ch <- m
+		case <-ctx.Done():
+			// the update carries on until its pending request returns, its
+			// remaining metrics are discarded
+			go func() {
+				for range metrics {
+				}
+			}()
+
+			logger.Warn("collector timed out", "name", name, "timeout", timeout)
+			collectorTimedOut(name)
+
+			return ErrNoData
+		}
+	}
 }
 
 // Collector is the interface a collector has to implement.
 type Collector interface {
-	// Get new metrics and expose them via prometheus registry.
-	Update(ch chan<- prometheus.Metric) error
+	// Get new metrics and expose them via prometheus registry. The context
+	// belongs to the scrape, use scrapeLogger to log with its ID.
+	Update(ctx context.Context, ch chan<- prometheus.Metric) error
+}
+
+type scrapeIDKey struct{}
+
+// newScrapeID generates a short random ID for a scrape.
+func newScrapeID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// scrapeLogger returns the logger with the ID of the scrape the context
+// belongs to.
+func scrapeLogger(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if id, ok := ctx.Value(scrapeIDKey{}).(string); ok {
+		return logger.With("scrape_id", id)
+	}
+
+	return logger
 }
 
 type typedDesc struct {
@@ -187,8 +577,16 @@ type typedDesc struct {
 	valueType prometheus.ValueType
 }
 
+// mustNewConstMetric creates a metric of the desc, sanitizing the label values
+// since many of them are free text set by the broadcasters, such as titles.
 func (d *typedDesc) mustNewConstMetric(value float64, labels ...string) prometheus.Metric {
-	return prometheus.MustNewConstMetric(d.desc, d.valueType, value, labels...)
+	// the labels may be the slice of the caller, which must be left as is
+	sanitized := make([]string, len(labels), len(labels)+1)
+	for i, label := range labels {
+		sanitized[i] = sanitizeLabel(label)
+	}
+
+	return prometheus.MustNewConstMetric(d.desc, d.valueType, value, withGroup(d.desc, sanitized)...)
 }
 
 var ErrNoData = errors.New("collector returned no data")
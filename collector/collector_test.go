@@ -0,0 +1,169 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// testCollector runs a collector as a prometheus.Collector, so its metrics can
+// be gathered by a registry and compared with the prometheus testutil.
+type testCollector struct {
+	t         *testing.T
+	collector Collector
+}
+
+func (c testCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c testCollector) Collect(ch chan<- prometheus.Metric) {
+	if err := c.collector.Update(context.Background(), ch); err != nil {
+		c.t.Errorf("update failed: %v", err)
+	}
+}
+
+// newTestCollector creates a collector with its factory, requesting a fake
+// Helix API serving the fixtures.
+func newTestCollector(t *testing.T, factory func(*slog.Logger, *helix.Client, *eventsub.Client, ChannelNames) (Collector, error), fixtures testutil.Fixtures, channelNames ...string) testCollector {
+	t.Helper()
+
+	server := testutil.NewServer(fixtures)
+	t.Cleanup(server.Close)
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := factory(slog.New(slog.NewTextHandler(io.Discard, nil)), client, nil, channelNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testCollector{t: t, collector: c}
+}
+
+func TestChannelLabelsConsistency(t *testing.T) {
+	factories := map[string]func(*slog.Logger, *helix.Client, *eventsub.Client, ChannelNames) (Collector, error){
+		"channel_up":                NewChannelUpCollector,
+		"channel_viewers_total":     NewChannelViewersTotalCollector,
+		"channel_followers_total":   NewChannelFollowersTotalCollector,
+		"channel_subscribers_total": NewChannelSubscriberTotalCollector,
+		"channel_stream_tags":       NewChannelStreamTagsCollector,
+	}
+
+	maxTags := *maxStreamTags
+	defer func() { *maxStreamTags = maxTags }()
+	*maxStreamTags = 10
+
+	for name, factory := range factories {
+		t.Run(name, func(t *testing.T) {
+			// the channel is configured with another casing than its login
+			c := newTestCollector(t, factory, testutil.DefaultFixtures, "SomeChannel")
+
+			ch := make(chan prometheus.Metric, 100)
+			if err := c.collector.Update(context.Background(), ch); err != nil {
+				t.Fatal(err)
+			}
+			close(ch)
+
+			if len(ch) == 0 {
+				t.Fatal("no metric collected")
+			}
+
+			for metric := range ch {
+				var m dto.Metric
+				if err := metric.Write(&m); err != nil {
+					t.Fatal(err)
+				}
+
+				labels := make(map[string]string)
+				for _, label := range m.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+
+				if login := labels["login"]; login != "somechannel" {
+					t.Errorf("%s has the login label %q, want %q", metric.Desc(), login, "somechannel")
+				}
+				if username := labels["username"]; username != "SomeChannel" {
+					t.Errorf("%s has the username label %q, want %q", metric.Desc(), username, "SomeChannel")
+				}
+			}
+		})
+	}
+}
+
+// assertUnlockedWhileSending checks the update does not hold the mutex while
+// it sends a metric, so the events updating the counts are not held up by a
+// slow scrape.
+func assertUnlockedWhileSending(t *testing.T, mu *sync.Mutex, update func(ch chan<- prometheus.Metric) error) {
+	t.Helper()
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	go func() {
+		done <- update(ch)
+		close(ch)
+	}()
+
+	// the metric is not received until the mutex was tried, so the update
+	// is blocked sending it
+	<-ch
+	if !mu.TryLock() {
+		t.Error("the mutex is held while sending the metrics")
+	} else {
+		mu.Unlock()
+	}
+
+	for range ch {
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExporterDescribe(t *testing.T) {
+	e := &Exporter{
+		scrapeDuration: prometheus.NewSummary(prometheus.SummaryOpts{Name: "describe_test_scrape_duration_seconds", Help: "Test."}),
+	}
+
+	ch := make(chan *prometheus.Desc, 100)
+	e.Describe(ch)
+	close(ch)
+
+	described := make(map[string]bool)
+	for desc := range ch {
+		described[desc.String()] = true
+	}
+
+	// every exporter level metric of Collect must be described, otherwise
+	// the registry treats it as unchecked
+	for _, desc := range []*prometheus.Desc{
+		scrapeDurationDesc(),
+		scrapeSuccessDesc(),
+		cacheHitsDesc(),
+		cacheMissesDesc(),
+		scopeMissingDesc(),
+		buildInfoDesc(),
+		lastScrapeErrorDesc(),
+		seriesTruncatedDesc(),
+		collectorTimeoutsDesc(),
+		helixRetriesDesc(),
+		helixCoalescedRequestsDesc(),
+		helixInflightRequestsDesc(),
+	} {
+		if !described[desc.String()] {
+			t.Errorf("%s is not described", desc)
+		}
+	}
+}
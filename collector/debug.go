@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// ChannelDebug is the state of a configured channel, as seen by the exporter,
+// to troubleshoot why a channel has no metrics.
+type ChannelDebug struct {
+	Login string `json:"login"`
+	// BroadcasterID is empty when the user of the channel is not cached,
+	// either because it was never resolved or because the login does not
+	// exist, see NotFound.
+	BroadcasterID string `json:"broadcaster_id,omitempty"`
+	NotFound      bool   `json:"not_found,omitempty"`
+	// Live is nil until the streams of the channel were requested by one of
+	// the collectors.
+	Live *bool `json:"live"`
+	// LastSuccessfulScrape is the last time each collector succeeded for a
+	// scrape which included the channel.
+	LastSuccessfulScrape map[string]time.Time `json:"last_successful_scrape"`
+	// Cache is when the cached entries of the channel expire, by cache name.
+	Cache map[string]time.Time `json:"cache"`
+}
+
+// channelStates records what the collectors learn of each channel for the
+// debug endpoint, keyed by lowercase login.
+var channelStates = struct {
+	mtx         sync.Mutex
+	live        map[string]bool
+	lastScrapes map[string]map[string]time.Time
+}{
+	live:        make(map[string]bool),
+	lastScrapes: make(map[string]map[string]time.Time),
+}
+
+// recordLive records which of the logins were live according to the streams
+// requested for them.
+func recordLive(logins []string, streams []helix.Stream) {
+	live := make(map[string]bool)
+	for _, s := range streams {
+		live[s.UserLogin] = true
+	}
+
+	channelStates.mtx.Lock()
+	defer channelStates.mtx.Unlock()
+
+	for _, login := range logins {
+		channelStates.live[strings.ToLower(login)] = live[strings.ToLower(login)]
+	}
+}
+
+// recordScrapeSuccess records that the collector succeeded for the logins of a
+// scrape.
+func recordScrapeSuccess(collector string, logins []string, at time.Time) {
+	channelStates.mtx.Lock()
+	defer channelStates.mtx.Unlock()
+
+	for _, login := range logins {
+		login = strings.ToLower(login)
+		if _, ok := channelStates.lastScrapes[login]; !ok {
+			channelStates.lastScrapes[login] = make(map[string]time.Time)
+		}
+
+		channelStates.lastScrapes[login][collector] = at
+	}
+}
+
+// DebugChannels returns the state of every configured channel. It only reads
+// what is already known, and never requests the Twitch API.
+func (e *Exporter) DebugChannels() []ChannelDebug {
+	channels := make([]ChannelDebug, 0, len(e.channelNames))
+
+	channelStates.mtx.Lock()
+	defer channelStates.mtx.Unlock()
+
+	for _, name := range e.channelNames {
+		login := strings.ToLower(name)
+		channel := ChannelDebug{
+			Login:                login,
+			LastSuccessfulScrape: make(map[string]time.Time),
+			Cache:                make(map[string]time.Time),
+		}
+
+		if user, expiresAt, ok := userCache.Peek(login); ok {
+			channel.Cache["user"] = expiresAt
+
+			if user, found := user.(helix.User); found {
+				channel.BroadcasterID = user.ID
+			} else {
+				channel.NotFound = true
+			}
+		}
+
+		if channel.BroadcasterID != "" {
+			if _, expiresAt, ok := channelInfoCache.Peek(channel.BroadcasterID); ok {
+				channel.Cache["channel_info"] = expiresAt
+			}
+		}
+
+		if live, ok := channelStates.live[login]; ok {
+			channel.Live = &live
+		}
+
+		for collector, at := range channelStates.lastScrapes[login] {
+			channel.LastSuccessfulScrape[collector] = at
+		}
+
+		channels = append(channels, channel)
+	}
+
+	return channels
+}
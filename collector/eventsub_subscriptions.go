@@ -0,0 +1,237 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var eventsubReconcileInterval = kingpin.Flag("eventsub.reconcile-interval",
+	"How often the eventsub subscriptions are listed to export their status.").
+	Default("60s").Duration()
+
+// resubscribeMaxBackoff caps the delay between the attempts to recreate a
+// subscription which keeps failing.
+const resubscribeMaxBackoff = time.Hour
+
+// resubscribeStatuses are the statuses of the subscriptions which are
+// recreated, the other ones would fail again.
+var resubscribeStatuses = map[string]bool{
+	"authorization_revoked":                true,
+	"notification_failures_exceeded":       true,
+	"webhook_callback_verification_failed": true,
+}
+
+type eventsubSubscriptionKey struct {
+	subscriptionType string
+	status           string
+}
+
+// resubscribeKey identifies a subscription independently of its ID, which
+// changes when it is recreated.
+type resubscribeKey struct {
+	subscriptionType string
+	version          string
+	condition        helix.EventSubCondition
+}
+
+// resubscribeBackoff tracks the attempts to recreate a subscription, until it
+// is enabled again.
+type resubscribeBackoff struct {
+	attempts int
+	next     time.Time
+}
+
+var (
+	eventsubSubscriptions      = map[eventsubSubscriptionKey]int{}
+	eventsubCost               = 0
+	eventsubReconciled         = false
+	eventsubResubscribes       = map[string]int{}
+	eventsubSubscriptionsMutex = sync.Mutex{}
+
+	// resubscribeBackoffs is only used by the reconciler, which is not run
+	// concurrently
+	resubscribeBackoffs = map[resubscribeKey]*resubscribeBackoff{}
+)
+
+type eventsubSubscriptionsCollector struct {
+	logger *slog.Logger
+
+	eventsubSubscriptions typedDesc
+	eventsubCostTotal     typedDesc
+	eventsubResubscribes  typedDesc
+}
+
+func init() {
+	// disabled by default since it relies on eventsub, which is disabled by default
+	registerCollector("eventsub_subscriptions", defaultDisabled, priorityNormal, NewEventsubSubscriptionsCollector)
+}
+
+func NewEventsubSubscriptionsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if eventsubClient == nil {
+		return nil, eventsub.ErrEventsubClientNotSet
+	}
+
+	// subscriptions are revoked by twitch without notifying the webhook when
+	// the authorization is removed, so they are listed periodically rather
+	// than on every scrape, since listing them pages through every
+	// subscription of the app
+	reconcileEventsubSubscriptions(logger, eventsubClient)
+
+	reconcileTicker := time.NewTicker(*eventsubReconcileInterval)
+	go func() {
+		for range reconcileTicker.C {
+			reconcileEventsubSubscriptions(logger, eventsubClient)
+		}
+	}()
+
+	c := eventsubSubscriptionsCollector{
+		logger: logger,
+
+		eventsubSubscriptions: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "eventsub", "subscriptions"),
+			"The number of eventsub subscriptions of the app, by type and status.",
+			[]string{"type", "status"}, nil,
+		), prometheus.GaugeValue},
+		eventsubCostTotal: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "eventsub", "cost_total"),
+			"The total cost of the eventsub subscriptions of the app.",
+			nil, nil,
+		), prometheus.GaugeValue},
+		eventsubResubscribes: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "eventsub", "resubscribes_total"),
+			"The number of revoked or failed eventsub subscriptions which were recreated.",
+			[]string{"type"}, nil,
+		), prometheus.CounterValue},
+	}
+
+	return c, nil
+}
+
+// reconcileEventsubSubscriptions lists the eventsub subscriptions and counts
+// them by type and status, then recreates the revoked or failed ones. The
+// previous counts are kept when the listing fails.
+func reconcileEventsubSubscriptions(logger *slog.Logger, eventsubClient *eventsub.Client) {
+	subscriptions, err := eventsubClient.Subscriptions()
+	if err != nil {
+		logger.Error("Failed to list eventsub subscriptions from Twitch helix API", "err", err)
+		return
+	}
+
+	counts := make(map[eventsubSubscriptionKey]int)
+	cost := 0
+	for _, s := range subscriptions {
+		counts[eventsubSubscriptionKey{subscriptionType: s.Type, status: s.Status}]++
+		cost += s.Cost
+	}
+
+	eventsubSubscriptionsMutex.Lock()
+	eventsubSubscriptions = counts
+	eventsubCost = cost
+	eventsubReconciled = true
+	eventsubSubscriptionsMutex.Unlock()
+
+	resubscribe(logger, eventsubClient, subscriptions)
+}
+
+// resubscribe recreates the revoked or failed subscriptions the collectors
+// subscribed to. A subscription which keeps failing, such as one missing a
+// scope, is retried with an exponential backoff starting at
+// --eventsub.reconcile-interval.
+func resubscribe(logger *slog.Logger, eventsubClient *eventsub.Client, subscriptions []helix.EventSubSubscription) {
+	// the backoff is reset once a subscription is enabled again, the failed
+	// copies still listed are then left alone
+	enabled := make(map[resubscribeKey]bool)
+	for _, s := range subscriptions {
+		if s.Status == "enabled" {
+			key := resubscribeKey{subscriptionType: s.Type, version: s.Version, condition: s.Condition}
+			enabled[key] = true
+			delete(resubscribeBackoffs, key)
+		}
+	}
+
+	now := time.Now()
+	for _, s := range subscriptions {
+		if !resubscribeStatuses[s.Status] {
+			continue
+		}
+
+		key := resubscribeKey{subscriptionType: s.Type, version: s.Version, condition: s.Condition}
+		if enabled[key] {
+			continue
+		}
+
+		backoff, ok := resubscribeBackoffs[key]
+		if !ok {
+			backoff = &resubscribeBackoff{}
+			resubscribeBackoffs[key] = backoff
+		}
+
+		// the same subscription may be listed several times when it failed
+		// repeatedly, which is covered by the backoff too
+		if now.Before(backoff.next) {
+			continue
+		}
+
+		err := eventsubClient.Resubscribe(s)
+		if errors.Is(err, eventsub.ErrSubscriptionNotRegistered) {
+			delete(resubscribeBackoffs, key)
+			continue
+		}
+
+		delay := *eventsubReconcileInterval
+		for i := 0; i < backoff.attempts && delay < resubscribeMaxBackoff; i++ {
+			delay *= 2
+		}
+
+		delay = min(delay, resubscribeMaxBackoff)
+		backoff.attempts++
+		backoff.next = now.Add(delay)
+
+		if err != nil {
+			logger.Error("Failed to resubscribe to eventsub subscription", "type", s.Type, "status", s.Status, "attempt", backoff.attempts, "next_attempt", backoff.next, "err", err)
+			continue
+		}
+
+		logger.Warn("resubscribed to eventsub subscription", "type", s.Type, "status", s.Status, "attempt", backoff.attempts)
+
+		eventsubSubscriptionsMutex.Lock()
+		eventsubResubscribes[s.Type]++
+		eventsubSubscriptionsMutex.Unlock()
+	}
+}
+
+func (c eventsubSubscriptionsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	// the counts are copied under the lock, so the reconciliation is not
+	// held up while the metrics are sent to the scrape
+	eventsubSubscriptionsMutex.Lock()
+	reconciled := eventsubReconciled
+	subscriptions := maps.Clone(eventsubSubscriptions)
+	cost := eventsubCost
+	resubscribed := maps.Clone(eventsubResubscribes)
+	eventsubSubscriptionsMutex.Unlock()
+
+	if !reconciled {
+		return ErrNoData
+	}
+
+	for key, count := range subscriptions {
+		ch <- c.eventsubSubscriptions.mustNewConstMetric(float64(count), key.subscriptionType, key.status)
+	}
+
+	ch <- c.eventsubCostTotal.mustNewConstMetric(float64(cost))
+
+	for subscriptionType, resubscribes := range resubscribed {
+		ch <- c.eventsubResubscribes.mustNewConstMetric(float64(resubscribes), subscriptionType)
+	}
+
+	return nil
+}
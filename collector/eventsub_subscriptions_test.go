@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEventsubSubscriptionsUpdateUnlocked(t *testing.T) {
+	eventsubSubscriptionsMutex.Lock()
+	subscriptions, cost, reconciled, resubscribes := eventsubSubscriptions, eventsubCost, eventsubReconciled, maps.Clone(eventsubResubscribes)
+	eventsubSubscriptions = map[eventsubSubscriptionKey]int{{subscriptionType: "channel.raid", status: "enabled"}: 2}
+	eventsubCost, eventsubReconciled = 2, true
+	eventsubResubscribes["channel.raid"] = 1
+	eventsubSubscriptionsMutex.Unlock()
+	defer func() {
+		eventsubSubscriptionsMutex.Lock()
+		eventsubSubscriptions, eventsubCost, eventsubReconciled, eventsubResubscribes = subscriptions, cost, reconciled, resubscribes
+		eventsubSubscriptionsMutex.Unlock()
+	}()
+
+	c := eventsubSubscriptionsCollector{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+
+		eventsubSubscriptions: typedDesc{prometheus.NewDesc("test_eventsub_subscriptions", "Test.", []string{"type", "status"}, nil), prometheus.GaugeValue},
+		eventsubCostTotal:     typedDesc{prometheus.NewDesc("test_eventsub_cost_total", "Test.", nil, nil), prometheus.GaugeValue},
+		eventsubResubscribes:  typedDesc{prometheus.NewDesc("test_eventsub_resubscribes_total", "Test.", []string{"type"}, nil), prometheus.CounterValue},
+	}
+
+	assertUnlockedWhileSending(t, &eventsubSubscriptionsMutex, func(ch chan<- prometheus.Metric) error {
+		return c.Update(t.Context(), ch)
+	})
+}
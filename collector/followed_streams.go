@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type followedStreamsCollector struct {
+	logger       *slog.Logger
+	client       *helix.Client
+	userID       string
+	channelNames ChannelNames
+
+	channelUp           typedDesc
+	channelViewersTotal typedDesc
+}
+
+func init() {
+	// disabled by default since it requires a user access token with the
+	// user:read:follows scope
+	registerUserCollector("followed_streams", defaultDisabled, priorityNormal, NewFollowedStreamsCollector)
+}
+
+func NewFollowedStreamsCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	// the followed streams are the ones of the user the access token belongs
+	// to, so it is looked up rather than configured
+	valid, tokenResp, err := client.ValidateToken(client.GetUserAccessToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, errors.New("a valid user access token is required for the followed streams")
+	}
+
+	c := followedStreamsCollector{
+		logger:       logger,
+		client:       client,
+		userID:       tokenResp.Data.UserID,
+		channelNames: channelNames,
+
+		// the metrics are the ones of the channel_up and channel_viewers_total
+		// collectors, so the help and labels must match theirs
+		channelUp: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_up"),
+			"Is the channel live.",
+			[]string{"username", "login", "game"}, nil,
+		), prometheus.GaugeValue},
+		channelViewersTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "channel_viewers_total"),
+			"How many viewers on this live channel. If stream is offline then this is absent.",
+			[]string{"username", "login", "game"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c followedStreamsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	// the configured channels are exported by the channel collectors, so
+	// they are skipped to not export them twice
+	configured := make(map[string]bool)
+	for _, n := range c.channelNames {
+		configured[strings.ToLower(n)] = true
+	}
+
+	cursor := ""
+	for {
+		streamsResp, err := c.client.GetFollowedStream(&helix.FollowedStreamsParams{
+			UserID: c.userID,
+			First:  100,
+			After:  cursor,
+		})
+
+		if err != nil {
+			logger.Error("Failed to collect followed streams from Twitch helix API", "err", err)
+			return err
+		}
+
+		if streamsResp.StatusCode != 200 {
+			logger.Error("Failed to collect followed streams from Twitch helix API", "err", streamsResp.ErrorMessage)
+			return helixError(streamsResp.StatusCode, streamsResp.ErrorMessage)
+		}
+
+		for _, s := range streamsResp.Data.Streams {
+			if configured[s.UserLogin] {
+				continue
+			}
+
+			// streams may be duplicated across pages as viewers come and go,
+			// so they are marked to only be exported once
+			configured[s.UserLogin] = true
+
+			ch <- c.channelUp.mustNewConstMetric(1, s.UserName, s.UserLogin, s.GameName)
+			ch <- c.channelViewersTotal.mustNewConstMetric(float64(s.ViewerCount), s.UserName, s.UserLogin, s.GameName)
+		}
+
+		cursor = streamsResp.Data.Pagination.Cursor
+		if cursor == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelGroups maps the logins of the channels to their group, it is set
+// with SetChannelGroups before the exporter is created.
+var channelGroups = map[string]string{}
+
+// groupedDescs maps the descs created with the group label to the index of
+// their login label, which the group is looked up by.
+var groupedDescs sync.Map
+
+// SetChannelGroups sets the group of the channels, added as a group label to
+// every metric of the channel. Channels without a group get an empty group
+// label. It must be called before the exporter is created.
+func SetChannelGroups(groups map[string]string) {
+	channelGroups = make(map[string]string, len(groups))
+	for login, group := range groups {
+		channelGroups[strings.ToLower(login)] = group
+	}
+}
+
+// newDesc creates the desc of a collector metric. Once channels are grouped,
+// the channel metrics, which are the ones with a login label, get a group
+// label too.
+func newDesc(fqName, help string, variableLabels []string, constLabels prometheus.Labels) *prometheus.Desc {
+	login := slices.Index(variableLabels, "login")
+	if len(channelGroups) == 0 || login < 0 {
+		return prometheus.NewDesc(fqName, help, variableLabels, constLabels)
+	}
+
+	desc := prometheus.NewDesc(fqName, help, append(slices.Clone(variableLabels), "group"), constLabels)
+	groupedDescs.Store(desc, login)
+
+	return desc
+}
+
+// withGroup appends the group of the channel to the label values of a metric
+// of the desc, when the desc was created with the group label.
+func withGroup(desc *prometheus.Desc, labels []string) []string {
+	login, ok := groupedDescs.Load(desc)
+	if !ok || login.(int) >= len(labels) {
+		return labels
+	}
+
+	return append(labels, channelGroups[strings.ToLower(labels[login.(int)])])
+}
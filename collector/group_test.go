@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestChannelGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups map[string]string
+		want   string
+	}{
+		{
+			name: "no groups",
+			want: `
+# HELP twitch_channel_up Is the channel live.
+# TYPE twitch_channel_up gauge
+twitch_channel_up{game="",login="otherchannel",username=""} 0
+twitch_channel_up{game="Just Chatting",login="somechannel",username="SomeChannel"} 1
+`,
+		},
+		{
+			name:   "grouped",
+			groups: map[string]string{"SomeChannel": "esports"},
+			want: `
+# HELP twitch_channel_up Is the channel live.
+# TYPE twitch_channel_up gauge
+twitch_channel_up{game="",group="",login="otherchannel",username=""} 0
+twitch_channel_up{game="Just Chatting",group="esports",login="somechannel",username="SomeChannel"} 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetChannelGroups(tt.groups)
+			defer SetChannelGroups(nil)
+
+			c := newTestCollector(t, NewChannelUpCollector, testutil.DefaultFixtures, "somechannel", "otherchannel")
+
+			// the comparison gathers with a pedantic registry, which checks
+			// the labels of the metrics against their desc
+			if err := promtestutil.CollectAndCompare(c, strings.NewReader(tt.want), "twitch_channel_up"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// APIBaseURL is the base URL of the Helix API used for the endpoints which are
+// not supported by the helix client.
+var APIBaseURL = helix.DefaultAPIBaseURL
+
+// UserAgent is sent with the requests the helix client does not make, it
+// should be the UserAgent the helix client was created with.
+var UserAgent = "twitch_exporter"
+
+// HTTPClient sends the requests to the endpoints which are not supported by the
+// helix client, it should be the client the helix client was created with.
+var HTTPClient helix.HTTPClient = http.DefaultClient
+
+var (
+	// ErrRateLimited is returned when Helix rejected a request since the rate
+	// limit was exhausted, the request may be retried once it resets.
+	ErrRateLimited = errors.New("rate limited by the Twitch API")
+	// ErrUnauthorized is returned when Helix rejected the access token, or it
+	// lacks the scope or role to make the request.
+	ErrUnauthorized = errors.New("unauthorized by the Twitch API")
+	// ErrChannelNotFound is returned when Helix did not find the channel of
+	// a request.
+	ErrChannelNotFound = errors.New("channel not found by the Twitch API")
+)
+
+// helixError returns the error of a failed Helix response, wrapping the typed
+// error of its status code so callers can tell failures apart with errors.Is.
+func helixError(statusCode int, message string) error {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", ErrRateLimited, message)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrUnauthorized, message)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrChannelNotFound, message)
+	}
+
+	return errors.New(message)
+}
+
+// getHelixClientID looks up the client ID the token of the client was issued
+// for, which is needed to request endpoints the helix client does not support.
+func getHelixClientID(client *helix.Client) (string, error) {
+	valid, tokenResp, err := client.ValidateToken(helixToken(client))
+	if err != nil {
+		return "", err
+	}
+
+	if !valid {
+		return "", errors.New("invalid access token")
+	}
+
+	return tokenResp.Data.ClientID, nil
+}
+
+// helixToken returns the token the client authenticates with, preferring the
+// user access token like the helix client does.
+func helixToken(client *helix.Client) string {
+	if token := client.GetUserAccessToken(); token != "" {
+		return token
+	}
+
+	return client.GetAppAccessToken()
+}
+
+// helixGet performs a GET request against an endpoint of the Helix API which is
+// not supported by the helix client, decoding the response body into data. The
+// status code of the response is returned so callers can handle not found
+// responses. An unauthorized request is sent once more after renewing the
+// token by the RefreshingClient in HTTPClient, like the requests of the helix
+// client.
+func helixGet(client *helix.Client, clientID, path string, query url.Values, data any) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, APIBaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Client-ID", clientID)
+	req.Header.Set("Authorization", "Bearer "+helixToken(client))
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, helixError(resp.StatusCode, fmt.Sprintf("unexpected status code %d from %s", resp.StatusCode, path))
+	}
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(data)
+}
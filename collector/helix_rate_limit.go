@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type helixRateLimitCollector struct {
+	logger *slog.Logger
+
+	helixRateLimitTotal     typedDesc
+	helixRateLimitRemaining typedDesc
+	helixRateLimitReset     typedDesc
+}
+
+func init() {
+	// it makes no request, so it is never skipped when the rate limit runs
+	// low, which is when it matters most
+	registerCollector("helix_rate_limit", defaultEnabled, priorityPassive, NewHelixRateLimitCollector)
+}
+
+func NewHelixRateLimitCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	c := helixRateLimitCollector{
+		logger: logger,
+
+		helixRateLimitTotal: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "helix_rate_limit_total"),
+			"The number of Helix API requests allowed per rate limit window, as reported by the most recent response.",
+			nil, nil,
+		), prometheus.GaugeValue},
+		helixRateLimitRemaining: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "helix_rate_limit_remaining"),
+			"The number of Helix API requests remaining in the rate limit window, as reported by the most recent response.",
+			nil, nil,
+		), prometheus.GaugeValue},
+		helixRateLimitReset: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "helix_rate_limit_reset_timestamp_seconds"),
+			"When the rate limit window of the Helix API resets, as reported by the most recent response.",
+			nil, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c helixRateLimitCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	rateBucketsMtx.Lock()
+	last := lastRateLimit
+	rateBucketsMtx.Unlock()
+
+	// nothing is known of the rate limit until a first response was received
+	if last.reset.IsZero() {
+		return nil
+	}
+
+	if last.limit > 0 {
+		ch <- c.helixRateLimitTotal.mustNewConstMetric(float64(last.limit))
+	}
+	ch <- c.helixRateLimitRemaining.mustNewConstMetric(float64(last.remaining))
+	ch <- c.helixRateLimitReset.mustNewConstMetric(float64(last.reset.Unix()))
+
+	return nil
+}
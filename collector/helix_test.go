@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+)
+
+func TestHelixGetUserAgent(t *testing.T) {
+	got := ""
+	handler := testutil.Handler(testutil.DefaultFixtures)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousURL, previousAgent := APIBaseURL, UserAgent
+	APIBaseURL, UserAgent = server.URL, "custom/1.0"
+	defer func() { APIBaseURL, UserAgent = previousURL, previousAgent }()
+
+	data := struct{}{}
+	if _, err := helixGet(client, "client-id", "/streams", url.Values{}, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "custom/1.0" {
+		t.Errorf("User-Agent = %q, want %q", got, "custom/1.0")
+	}
+}
+
+func TestHelixError(t *testing.T) {
+	sentinels := []error{ErrRateLimited, ErrUnauthorized, ErrChannelNotFound}
+
+	tests := []struct {
+		statusCode int
+		want       error
+	}{
+		{statusCode: http.StatusTooManyRequests, want: ErrRateLimited},
+		{statusCode: http.StatusUnauthorized, want: ErrUnauthorized},
+		{statusCode: http.StatusForbidden, want: ErrUnauthorized},
+		{statusCode: http.StatusNotFound, want: ErrChannelNotFound},
+		{statusCode: http.StatusInternalServerError, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			err := helixError(tt.statusCode, "message")
+			if err == nil {
+				t.Fatal("no error returned")
+			}
+
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %t, want %t", err, sentinel, got, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestHelixGetError(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       error
+	}{
+		{statusCode: http.StatusTooManyRequests, want: ErrRateLimited},
+		{statusCode: http.StatusForbidden, want: ErrUnauthorized},
+		{statusCode: http.StatusNotFound, want: ErrChannelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client, err := testutil.NewClient(server)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			previous := APIBaseURL
+			APIBaseURL = server.URL
+			defer func() { APIBaseURL = previous }()
+
+			data := struct{}{}
+			statusCode, err := helixGet(client, "client-id", "/teams", url.Values{}, &data)
+			if statusCode != tt.statusCode {
+				t.Errorf("status code = %d, want %d", statusCode, tt.statusCode)
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", err, tt.want)
+			}
+		})
+	}
+}
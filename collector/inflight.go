@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var maxInflight = kingpin.Flag("twitch.max-inflight",
+	"Maximum number of Helix API requests in flight at once, across the collectors and concurrent scrapes. 0 disables the limit.").
+	Default("10").Int()
+
+func helixInflightRequestsDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "helix", "inflight_requests"),
+		"Number of Helix API requests in flight.",
+		nil,
+		nil,
+	)
+}
+
+var helixInflightRequests atomic.Int64
+
+// InflightClient is an HTTP client for the Helix API which bounds the number
+// of requests in flight, so concurrent scrapes do not send bursts of requests
+// even when the rate limit would allow them.
+type InflightClient struct {
+	next helix.HTTPClient
+	sem  chan struct{}
+}
+
+// NewInflightClient creates an InflightClient sending at most
+// --twitch.max-inflight requests at once with next.
+func NewInflightClient(next helix.HTTPClient) *InflightClient {
+	c := &InflightClient{next: next}
+	if *maxInflight > 0 {
+		c.sem = make(chan struct{}, *maxInflight)
+	}
+
+	return c
+}
+
+// Do sends the request once fewer than --twitch.max-inflight requests are in
+// flight, or gives up when the context of the request is done first.
+func (c *InflightClient) Do(req *http.Request) (*http.Response, error) {
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	helixInflightRequests.Add(1)
+	defer helixInflightRequests.Add(-1)
+
+	return c.next.Do(req)
+}
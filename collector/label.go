@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+var maxLabelLength = kingpin.Flag("web.max-label-length",
+	"Maximum number of characters of a label value, longer values such as stream titles are truncated. 0 disables the limit.").
+	Default("128").Int()
+
+// labelHashLength is the number of characters of the hash suffix of a
+// truncated label value, a tilde and 8 hex digits.
+const labelHashLength = 9
+
+// sanitizeLabel makes a free text label value safe to export: invalid UTF-8,
+// which the exposition format rejects, is replaced, control characters are
+// stripped and the value is truncated to --web.max-label-length characters.
+// A truncated value ends with a short hash of the whole value, so values which
+// only differ past the limit do not collide into duplicate series.
+func sanitizeLabel(value string) string {
+	value = strings.ToValidUTF8(value, string(unicode.ReplacementChar))
+
+	runes := []rune{}
+	for _, r := range value {
+		if !unicode.IsControl(r) {
+			runes = append(runes, r)
+		}
+	}
+
+	if *maxLabelLength <= 0 || len(runes) <= *maxLabelLength {
+		return string(runes)
+	}
+
+	// a limit too short for the hash is a plain truncation
+	if *maxLabelLength <= labelHashLength {
+		return string(runes[:*maxLabelLength])
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(string(runes)))
+
+	return fmt.Sprintf("%s~%08x", string(runes[:*maxLabelLength-labelHashLength]), h.Sum32())
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSanitizeLabel(t *testing.T) {
+	previous := *maxLabelLength
+	*maxLabelLength = 128
+	defer func() { *maxLabelLength = previous }()
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "short value kept", value: "Hello 🎮", want: "Hello 🎮"},
+		{name: "control characters stripped", value: "Hello\nworld\t!", want: "Helloworld!"},
+		{name: "invalid UTF-8 replaced", value: "Hello \xff", want: "Hello �"},
+		{name: "long value truncated with a hash", value: strings.Repeat("🎮", 500), want: strings.Repeat("🎮", 119) + "~bb62a6ad"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeLabel(tt.value)
+			if got != tt.want {
+				t.Errorf("sanitizeLabel() = %q, want %q", got, tt.want)
+			}
+
+			if n := utf8.RuneCountInString(got); n > *maxLabelLength {
+				t.Errorf("sanitizeLabel() has %d characters, want at most %d", n, *maxLabelLength)
+			}
+		})
+	}
+}
+
+func TestSanitizeLabelTruncatedCollision(t *testing.T) {
+	previous := *maxLabelLength
+	*maxLabelLength = 128
+	defer func() { *maxLabelLength = previous }()
+
+	// titles of 500 emojis only differing at their end
+	title := strings.Repeat("🎮", 499)
+	a, b := sanitizeLabel(title+"🔥"), sanitizeLabel(title+"💀")
+
+	if a == b {
+		t.Errorf("truncated titles collide: %q", a)
+	}
+
+	if n := utf8.RuneCountInString(a); n != *maxLabelLength {
+		t.Errorf("truncated title has %d characters, want %d", n, *maxLabelLength)
+	}
+}
+
+func TestMustNewConstMetricKeepsLabels(t *testing.T) {
+	labels := []string{"somechannel", "Hello\nworld"}
+	desc := typedDesc{prometheus.NewDesc("test", "Test metric.", []string{"username", "title"}, nil), prometheus.GaugeValue}
+	desc.mustNewConstMetric(1, labels...)
+
+	if labels[1] != "Hello\nworld" {
+		t.Errorf("labels of the caller changed to %q", labels)
+	}
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var maxSeries = kingpin.Flag("web.max-series",
+	"Maximum number of series exported by the collectors on a scrape, the series of a collector past it are dropped. 0 disables the limit.").
+	Default("0").Int()
+
+func seriesTruncatedDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "series_truncated_total"),
+		"Number of series of a collector dropped since a scrape exceeded --web.max-series.",
+		[]string{"collector"},
+		nil,
+	)
+}
+
+var (
+	seriesTruncatedMtx = sync.Mutex{}
+	seriesTruncated    = make(map[string]int)
+)
+
+type seriesLimiterKey struct{}
+
+// seriesLimiter counts the series exported by the collectors of a scrape, so
+// the scrape stops growing once it reached the limit.
+type seriesLimiter struct {
+	max   int64
+	count atomic.Int64
+}
+
+// newSeriesLimiter returns the limiter of a scrape, or nil when the number of
+// series is not limited.
+func newSeriesLimiter() *seriesLimiter {
+	if *maxSeries <= 0 {
+		return nil
+	}
+
+	return &seriesLimiter{max: int64(*maxSeries)}
+}
+
+// scrapeSeriesLimiter returns the limiter of the scrape the context belongs
+// to, or nil when the number of series is not limited.
+func scrapeSeriesLimiter(ctx context.Context) *seriesLimiter {
+	limiter, _ := ctx.Value(seriesLimiterKey{}).(*seriesLimiter)
+	return limiter
+}
+
+// allow counts a series of the scrape, and reports whether it is within the
+// limit.
+func (l *seriesLimiter) allow() bool {
+	return l.count.Add(1) <= l.max
+}
+
+// truncated records the series of a collector which were dropped since the
+// scrape exceeded the limit.
+func (l *seriesLimiter) truncated(logger *slog.Logger, collector string, dropped int) {
+	logger.Warn("scrape exceeded the maximum number of series, dropping the remaining series of the collector", "collector", collector, "max_series", l.max, "dropped", dropped)
+
+	seriesTruncatedMtx.Lock()
+	defer seriesTruncatedMtx.Unlock()
+
+	seriesTruncated[collector] += dropped
+}
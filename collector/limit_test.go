@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// seriesCollector exports the given number of series.
+type seriesCollector struct {
+	desc   typedDesc
+	series int
+}
+
+func (c seriesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	for i := range c.series {
+		ch <- c.desc.mustNewConstMetric(1, strconv.Itoa(i))
+	}
+
+	return nil
+}
+
+func TestSeriesLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxSeries     int
+		series        []int
+		wantForwarded []int
+		wantDropped   []int
+	}{
+		{name: "no limit", maxSeries: 0, series: []int{8, 4}, wantForwarded: []int{8, 4}, wantDropped: []int{0, 0}},
+		{name: "under the limit", maxSeries: 20, series: []int{8, 4}, wantForwarded: []int{8, 4}, wantDropped: []int{0, 0}},
+		{name: "at the limit", maxSeries: 12, series: []int{8, 4}, wantForwarded: []int{8, 4}, wantDropped: []int{0, 0}},
+		{name: "over the limit", maxSeries: 5, series: []int{8, 4}, wantForwarded: []int{5, 0}, wantDropped: []int{3, 4}},
+	}
+
+	previous := *maxSeries
+	defer func() { *maxSeries = previous }()
+
+	desc := typedDesc{prometheus.NewDesc("test_series", "Test series.", []string{"id"}, nil), prometheus.GaugeValue}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*maxSeries = tt.maxSeries
+			ctx := context.WithValue(context.Background(), seriesLimiterKey{}, newSeriesLimiter())
+
+			for i, series := range tt.series {
+				name := "limit_test_" + strconv.Itoa(i)
+
+				seriesTruncatedMtx.Lock()
+				delete(seriesTruncated, name)
+				seriesTruncatedMtx.Unlock()
+
+				ch := make(chan prometheus.Metric, series)
+				if err := update(ctx, logger, name, seriesCollector{desc: desc, series: series}, ch); err != nil {
+					t.Fatal(err)
+				}
+
+				if len(ch) != tt.wantForwarded[i] {
+					t.Errorf("collector %d forwarded %d series, want %d", i, len(ch), tt.wantForwarded[i])
+				}
+
+				seriesTruncatedMtx.Lock()
+				dropped := seriesTruncated[name]
+				seriesTruncatedMtx.Unlock()
+
+				if dropped != tt.wantDropped[i] {
+					t.Errorf("collector %d dropped %d series, want %d", i, dropped, tt.wantDropped[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var liveOnly = kingpin.Flag("twitch.live-only",
+	"Only request the live channels in the expensive per channel collectors, looked up once at the start of each scrape.").
+	Default("false").Bool()
+
+// liveOnlyCollectors are the expensive per channel collectors restricted to
+// the live channels with --twitch.live-only.
+var liveOnlyCollectors = map[string]bool{
+	"channel_followers_total":   true,
+	"channel_subscribers_total": true,
+	"channel_clips_total":       true,
+}
+
+type liveChannelsKey struct{}
+
+// withLiveChannels adds the lookup of which of the channels of the scrape are
+// live to the context, so the live only collectors can skip the others. The
+// lookup is made once, by the first live only collector which runs, so a
+// scrape without any makes no request. When the lookup fails, the live only
+// collectors request every channel rather than none.
+func withLiveChannels(ctx context.Context, client *helix.Client, logger *slog.Logger, channelNames ChannelNames) context.Context {
+	lookup := sync.OnceValues(func() (ChannelNames, error) {
+		streams, err := getStreams(client, channelNames)
+		if err != nil {
+			scrapeLogger(ctx, logger).Error("Failed to look up the live channels, requesting every channel", "err", err)
+			return nil, err
+		}
+
+		live := make(map[string]bool)
+		for _, s := range streams {
+			live[strings.ToLower(s.UserLogin)] = true
+		}
+
+		liveChannels := ChannelNames{}
+		for _, n := range channelNames {
+			if live[strings.ToLower(n)] {
+				liveChannels = append(liveChannels, n)
+			}
+		}
+
+		return liveChannels, nil
+	})
+
+	return context.WithValue(ctx, liveChannelsKey{}, lookup)
+}
+
+// liveOnlyCollector restricts the channels a collector requests to the live
+// ones, through the channels of the scrape.
+type liveOnlyCollector struct {
+	Collector
+}
+
+func (c liveOnlyCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if lookup, ok := ctx.Value(liveChannelsKey{}).(func() (ChannelNames, error)); ok {
+		if live, err := lookup(); err == nil {
+			ctx = context.WithValue(ctx, scrapeChannelsKey{}, live)
+		}
+	}
+
+	return c.Collector.Update(ctx, ch)
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelsCollector records the channels of the scrape it was updated with.
+type channelsCollector struct {
+	channels *[]string
+}
+
+func (c channelsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	*c.channels = scrapeChannels(ctx, ChannelNames{"somechannel", "otherchannel"})
+	return nil
+}
+
+func TestWithLiveChannels(t *testing.T) {
+	tests := []struct {
+		name         string
+		updates      int
+		wantRequests int32
+	}{
+		{name: "no live only collector", updates: 0, wantRequests: 0},
+		{name: "one live only collector", updates: 1, wantRequests: 1},
+		{name: "several live only collectors", updates: 3, wantRequests: 1},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newCountingClient(t)
+			ctx := withLiveChannels(context.Background(), client, logger, ChannelNames{"somechannel", "otherchannel"})
+
+			for range tt.updates {
+				channels := []string{}
+				if err := (liveOnlyCollector{channelsCollector{&channels}}).Update(ctx, nil); err != nil {
+					t.Fatal(err)
+				}
+
+				// only somechannel is live in the fixtures
+				if !slices.Equal(channels, []string{"somechannel"}) {
+					t.Errorf("channels = %v, want the live ones", channels)
+				}
+			}
+
+			if n := requests.Load(); n != tt.wantRequests {
+				t.Errorf("API requested %d times, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The priorities of the collectors, when the rate limit is almost exhausted
+// only the collectors of the highest priority run, so the cheap core metrics
+// are still collected while the expensive collectors would spend the rate
+// limit. The low priority collectors walk pages of results, so they start once
+// the higher priorities are done and the rate limit is checked again. The
+// passive collectors make no request, so they run once the others are done to
+// see the responses of the scrape and are never skipped.
+const (
+	priorityPassive = -1
+	priorityLow     = 0
+	priorityNormal  = 1
+	priorityHigh    = 2
+)
+
+var rateLimitFloor = kingpin.Flag("twitch.rate-limit-floor",
+	"Number of remaining Helix API requests under which the collectors below the highest priority are skipped until the rate limit resets.").
+	Default("10").Int()
+
+var collectorPriorities = make(map[string]int)
+
+// rateBucket is the rate limit of an access token, as reported by the last
+// response to a request made with it.
+type rateBucket struct {
+	remaining int
+	reset     time.Time
+}
+
+var (
+	rateBucketsMtx = sync.Mutex{}
+	rateBuckets    = make(map[string]rateBucket) // authorization header -> bucket
+	// lastRateLimit is the rate limit reported by the most recent response,
+	// whichever access token it was made with
+	lastRateLimit rateLimit
+)
+
+// rateLimit is the rate limit reported by a response.
+type rateLimit struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// recordRateLimit keeps the rate limit reported by the response, responses
+// without rate limit headers are ignored.
+func recordRateLimit(req *http.Request, resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	rateBucketsMtx.Lock()
+	defer rateBucketsMtx.Unlock()
+
+	rateBuckets[req.Header.Get("Authorization")] = rateBucket{remaining: remaining, reset: time.Unix(reset, 0)}
+
+	// the limit is only needed by the rate limit collector, so a response
+	// without it still counts toward the buckets
+	limit, _ := strconv.Atoi(resp.Header.Get("Ratelimit-Limit"))
+	lastRateLimit = rateLimit{limit: limit, remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// rateLimitExhausted reports whether the rate limit of an access token is
+// under --twitch.rate-limit-floor and has not reset yet.
+func rateLimitExhausted() bool {
+	rateBucketsMtx.Lock()
+	defer rateBucketsMtx.Unlock()
+
+	now := time.Now()
+	for _, bucket := range rateBuckets {
+		if bucket.remaining < *rateLimitFloor && now.Before(bucket.reset) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectorTier holds the collectors of a priority.
+type collectorTier struct {
+	priority   int
+	collectors map[string]Collector
+}
+
+// collectorTiers groups the collectors by priority, from the highest to the
+// lowest.
+func collectorTiers(collectors map[string]Collector) []collectorTier {
+	byPriority := make(map[int]map[string]Collector)
+	for name, c := range collectors {
+		priority := collectorPriorities[name]
+		if byPriority[priority] == nil {
+			byPriority[priority] = make(map[string]Collector)
+		}
+
+		byPriority[priority][name] = c
+	}
+
+	tiers := []collectorTier{}
+	for priority, collectors := range byPriority {
+		tiers = append(tiers, collectorTier{priority: priority, collectors: collectors})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].priority > tiers[j].priority
+	})
+
+	return tiers
+}
+
+// skippedCollector stands in for a collector which is skipped since the rate
+// limit is almost exhausted, it returns no data.
+type skippedCollector struct{}
+
+func (skippedCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return ErrNoData
+}
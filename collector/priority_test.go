@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// funcCollector runs a function as the update of a collector.
+type funcCollector func(ctx context.Context) error
+
+func (f funcCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return f(ctx)
+}
+
+func TestCollectPriorities(t *testing.T) {
+	tests := []struct {
+		name         string
+		exhaustsRate bool
+		wantLowRun   bool
+	}{
+		{name: "rate limit left", wantLowRun: true},
+		{name: "rate limit exhausted during the scrape", exhaustsRate: true, wantLowRun: false},
+	}
+
+	floor := *rateLimitFloor
+	defer func() { *rateLimitFloor = floor }()
+	*rateLimitFloor = 10
+
+	resetBuckets := func() {
+		rateBucketsMtx.Lock()
+		rateBuckets = make(map[string]rateBucket)
+		rateBucketsMtx.Unlock()
+	}
+	defer resetBuckets()
+
+	collectorPriorities["priority_test_high"] = priorityHigh
+	collectorPriorities["priority_test_low"] = priorityLow
+	defer func() {
+		delete(collectorPriorities, "priority_test_high")
+		delete(collectorPriorities, "priority_test_low")
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetBuckets()
+
+			highDone := atomic.Bool{}
+			lowRun := atomic.Bool{}
+			lowStartedEarly := atomic.Bool{}
+
+			e := &Exporter{
+				Collectors: map[string]Collector{
+					"priority_test_high": funcCollector(func(ctx context.Context) error {
+						time.Sleep(50 * time.Millisecond)
+
+						if tt.exhaustsRate {
+							rateBucketsMtx.Lock()
+							rateBuckets["Bearer test"] = rateBucket{remaining: 1, reset: time.Now().Add(time.Minute)}
+							rateBucketsMtx.Unlock()
+						}
+
+						highDone.Store(true)
+						return nil
+					}),
+					"priority_test_low": funcCollector(func(ctx context.Context) error {
+						lowStartedEarly.Store(!highDone.Load())
+						lowRun.Store(true)
+						return nil
+					}),
+				},
+				logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+				rotation:       newRotation(nil),
+				scrapeDuration: prometheus.NewSummary(prometheus.SummaryOpts{Name: "priority_test_scrape_duration_seconds", Help: "Test."}),
+			}
+
+			ch := make(chan prometheus.Metric)
+			go func() {
+				e.Collect(ch)
+				close(ch)
+			}()
+			for range ch {
+			}
+
+			if lowStartedEarly.Load() {
+				t.Error("the low priority collector started before the high priority one was done")
+			}
+
+			if got := lowRun.Load(); got != tt.wantLowRun {
+				t.Errorf("low priority collector run = %t, want %t", got, tt.wantLowRun)
+			}
+		})
+	}
+}
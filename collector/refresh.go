@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/nicklaw5/helix/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+// TokenRefresher renews the access token of a client, it is called when a
+// request sent with the token of a registered client is rejected as
+// unauthorized. It is set by the main package, which persists the renewed
+// tokens.
+var TokenRefresher func(client *helix.Client) error
+
+// tokenRefreshes makes the collectors rejected at once renew the token of a
+// client only once, since a refresh token may only be used once.
+var tokenRefreshes singleflight.Group
+
+var (
+	tokenClientsMtx sync.Mutex
+	tokenClients    []*helix.Client
+	// renewedTokens holds the token each client had before its last renewal
+	// by refreshToken, so a request rejected with a token which was renewed
+	// meanwhile is sent again with the current one rather than renewing it
+	// twice. Only the latest renewed token is kept, so it does not grow with
+	// every renewal.
+	renewedTokens = make(map[*helix.Client]string)
+)
+
+// RegisterTokenClient registers a client whose access token is renewed with
+// TokenRefresher when a request sent with it is rejected as unauthorized.
+func RegisterTokenClient(client *helix.Client) {
+	tokenClientsMtx.Lock()
+	defer tokenClientsMtx.Unlock()
+
+	tokenClients = append(tokenClients, client)
+}
+
+// tokenClient returns the registered client a request was authenticated for,
+// or nil if the token is not one of a registered client.
+func tokenClient(token string) *helix.Client {
+	tokenClientsMtx.Lock()
+	defer tokenClientsMtx.Unlock()
+
+	for _, client := range tokenClients {
+		if helixToken(client) == token {
+			return client
+		}
+	}
+
+	// the current tokens come first, another client may have been given the
+	// token a client had before its renewal
+	for client, renewed := range renewedTokens {
+		if renewed == token {
+			return client
+		}
+	}
+
+	return nil
+}
+
+// refreshToken renews the access token of the client with TokenRefresher.
+func refreshToken(client *helix.Client) error {
+	if TokenRefresher == nil {
+		return fmt.Errorf("%w: no token refresher", ErrUnauthorized)
+	}
+
+	_, err, _ := tokenRefreshes.Do(fmt.Sprintf("%p", client), func() (any, error) {
+		token := helixToken(client)
+		if err := TokenRefresher(client); err != nil {
+			return nil, err
+		}
+
+		tokenClientsMtx.Lock()
+		renewedTokens[client] = token
+		tokenClientsMtx.Unlock()
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// RefreshingClient is an HTTP client for the Helix API which renews the token
+// of a registered client when a request is rejected as unauthorized, and sends
+// the request once more with the renewed token. It works alike for app and
+// user access tokens, so the helix clients must not be given the refresh
+// token, otherwise they renew the user access token on every 401 themselves.
+type RefreshingClient struct {
+	next helix.HTTPClient
+}
+
+// NewRefreshingClient creates a RefreshingClient sending the requests with
+// next.
+func NewRefreshingClient(next helix.HTTPClient) *RefreshingClient {
+	return &RefreshingClient{next: next}
+}
+
+// Do sends the request. A request which is still rejected after renewing the
+// token is answered with the response of the retry, so the collectors see the
+// status returned by the API.
+func (c *RefreshingClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.next.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return resp, nil
+	}
+
+	client := tokenClient(token)
+	if client == nil {
+		return resp, nil
+	}
+
+	// the token may have been renewed by another request meanwhile
+	if helixToken(client) == token {
+		if err := refreshToken(client); err != nil || helixToken(client) == token {
+			return resp, nil
+		}
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", "Bearer "+helixToken(client))
+
+	resp.Body.Close()
+
+	return c.next.Do(retry)
+}
@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+func TestRefreshingClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       bool
+		renewed    string
+		wantStatus int
+		wantHits   int
+	}{
+		{name: "app token renewed", renewed: "valid", wantStatus: http.StatusOK, wantHits: 2},
+		{name: "user token renewed", user: true, renewed: "valid", wantStatus: http.StatusOK, wantHits: 2},
+		{name: "renewed token rejected", renewed: "still-expired", wantStatus: http.StatusUnauthorized, wantHits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				if r.Header.Get("Authorization") != "Bearer valid" {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"Unauthorized","status":401,"message":"Invalid OAuth token"}`))
+					return
+				}
+
+				w.Write([]byte(`{"data":[],"pagination":{}}`))
+			}))
+			defer server.Close()
+
+			opts := &helix.Options{
+				ClientID:   "client-id",
+				APIBaseURL: server.URL,
+				HTTPClient: NewRefreshingClient(server.Client()),
+			}
+			if tt.user {
+				opts.UserAccessToken = "expired"
+			} else {
+				opts.AppAccessToken = "expired"
+			}
+
+			client, err := helix.NewClient(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			RegisterTokenClient(client)
+
+			refreshes := 0
+			previous := TokenRefresher
+			TokenRefresher = func(client *helix.Client) error {
+				refreshes++
+				if tt.user {
+					client.SetUserAccessToken(tt.renewed)
+				} else {
+					client.SetAppAccessToken(tt.renewed)
+				}
+				return nil
+			}
+			defer func() { TokenRefresher = previous }()
+
+			resp, err := client.GetStreams(&helix.StreamsParams{UserLogins: []string{"somechannel"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if refreshes != 1 {
+				t.Errorf("token renewed %d times, want 1", refreshes)
+			}
+
+			if hits != tt.wantHits {
+				t.Errorf("API requested %d times, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRenewedTokens(t *testing.T) {
+	client, err := helix.NewClient(&helix.Options{ClientID: "client-id", UserAccessToken: "token-0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterTokenClient(client)
+
+	renewals := 0
+	previous := TokenRefresher
+	TokenRefresher = func(client *helix.Client) error {
+		renewals++
+		client.SetUserAccessToken(fmt.Sprintf("token-%d", renewals))
+		return nil
+	}
+	defer func() { TokenRefresher = previous }()
+
+	// the user access tokens of a long running exporter are renewed hourly
+	for range 3 {
+		if err := refreshToken(client); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokenClientsMtx.Lock()
+	renewed, ok := renewedTokens[client]
+	tokenClientsMtx.Unlock()
+
+	if !ok || renewed != "token-2" {
+		t.Errorf("renewed token = %q, want only the latest one %q", renewed, "token-2")
+	}
+
+	tests := []struct {
+		token string
+		want  *helix.Client
+	}{
+		{token: "token-3", want: client},
+		{token: "token-2", want: client},
+		{token: "token-1", want: nil},
+		{token: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := tokenClient(tt.token); got != tt.want {
+			t.Errorf("tokenClient(%q) = %p, want %p", tt.token, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var maxRetries = kingpin.Flag("twitch.max-retries",
+	"Maximum number of times a Helix API request is retried on transient errors.").
+	Default("2").Int()
+
+var requestTimeout = kingpin.Flag("twitch.request-timeout",
+	"Maximum duration of a Helix API request, a request which times out is retried like a transient error.").
+	Default("10s").Duration()
+
+const (
+	// retryBaseDelay is the delay before the first retry, doubled on every
+	// following attempt.
+	retryBaseDelay = 500 * time.Millisecond
+	// retryMaxDelay is the longest delay before a retry when
+	// --collector.timeout is not set, a rate limited request resetting later
+	// is not retried.
+	retryMaxDelay = 10 * time.Second
+)
+
+func helixRetriesDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "helix", "retries_total"),
+		"Number of Helix API requests retried after a transient error.",
+		[]string{"endpoint"},
+		nil,
+	)
+}
+
+var (
+	helixRetriesMtx = sync.Mutex{}
+	helixRetries    = make(map[string]int)
+)
+
+// RetryClient is an HTTP client for the Helix API which retries requests that
+// failed with a transient error, backing off exponentially between attempts.
+type RetryClient struct {
+	logger *slog.Logger
+	client *http.Client
+}
+
+// NewRetryClient creates a RetryClient sending the requests with the default
+// HTTP transport.
+func NewRetryClient(logger *slog.Logger) *RetryClient {
+	return &RetryClient{
+		logger: logger,
+		client: &http.Client{
+			Timeout: *requestTimeout,
+		},
+	}
+}
+
+// Do sends the request, retrying it at most --twitch.max-retries times on
+// network timeouts and on 429, 500, 502 and 503 responses. Rate limited
+// requests are retried once the rate limit resets, unless it resets later than
+// the update of a collector may last.
+func (c *RetryClient) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if resp != nil {
+			recordRateLimit(req, resp)
+		}
+
+		if attempt >= *maxRetries || !retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := min(retryBaseDelay<<attempt, maxRetryDelay())
+		if resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				// the collector would time out before the rate limit resets,
+				// while the request still holds its in flight slot
+				delay = rateLimitResetDelay(resp, delay)
+				if delay > maxRetryDelay() {
+					return resp, err
+				}
+			}
+
+			resp.Body.Close()
+		}
+
+		// the body of the request has been consumed by the previous attempt
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req.Body = body
+		}
+
+		endpoint := helixEndpoint(req.URL)
+		c.logger.Warn("retrying Helix API request", "endpoint", endpoint, "attempt", attempt+1, "delay", delay, "err", err)
+
+		helixRetriesMtx.Lock()
+		helixRetries[endpoint]++
+		helixRetriesMtx.Unlock()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable reports whether a request which got the response or error may
+// succeed when sent again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+
+	return false
+}
+
+// rateLimitResetDelay returns how long to wait until the rate limit reported by
+// the response resets, or fallback when the response has no reset header.
+func rateLimitResetDelay(resp *http.Response, fallback time.Duration) time.Duration {
+	reset, err := strconv.ParseInt(resp.Header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return fallback
+	}
+
+	delay := time.Until(time.Unix(reset, 0))
+	if delay <= 0 {
+		return fallback
+	}
+
+	return delay
+}
+
+// maxRetryDelay returns the longest delay before a retry, which is the default
+// timeout of the collectors so a retry does not outlive the update it is for.
+func maxRetryDelay() time.Duration {
+	if *defaultCollectorTimeout > 0 {
+		return *defaultCollectorTimeout
+	}
+
+	return retryMaxDelay
+}
+
+// helixEndpoint returns the path of the url relative to the Helix API base URL,
+// so it can be used as a label value.
+func helixEndpoint(u *url.URL) string {
+	base, err := url.Parse(APIBaseURL)
+	if err != nil {
+		return u.Path
+	}
+
+	return strings.TrimPrefix(u.Path, strings.TrimSuffix(base.Path, "/"))
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []int
+		reset      time.Duration
+		wantStatus int
+		wantHits   int
+	}{
+		{name: "transient error retried", responses: []int{http.StatusServiceUnavailable, http.StatusOK}, wantStatus: http.StatusOK, wantHits: 2},
+		{name: "rate limit resetting soon retried", responses: []int{http.StatusTooManyRequests, http.StatusOK}, reset: time.Second, wantStatus: http.StatusOK, wantHits: 2},
+		{name: "rate limit resetting late not retried", responses: []int{http.StatusTooManyRequests, http.StatusOK}, reset: time.Hour, wantStatus: http.StatusTooManyRequests, wantHits: 1},
+	}
+
+	retries, timeout := *maxRetries, *requestTimeout
+	*maxRetries, *requestTimeout = 2, time.Second
+	defer func() { *maxRetries, *requestTimeout = retries, timeout }()
+
+	// the rate limit of the responses must not skip the collectors of the
+	// other tests
+	defer func() {
+		rateBucketsMtx.Lock()
+		rateBuckets = make(map[string]rateBucket)
+		rateBucketsMtx.Unlock()
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Ratelimit-Remaining", "0")
+				w.Header().Set("Ratelimit-Reset", strconv.FormatInt(time.Now().Add(tt.reset).Unix(), 10))
+				w.WriteHeader(tt.responses[hits])
+				hits++
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/streams", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := NewRetryClient(slog.New(slog.NewTextHandler(io.Discard, nil))).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if hits != tt.wantHits {
+				t.Errorf("API requested %d times, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
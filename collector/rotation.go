@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var scrapeBatchSize = kingpin.Flag("twitch.scrape-batch-size",
+	"Number of channels refreshed on each scrape, the others serving their last values. 0 refreshes every channel on every scrape.").
+	Default("0").Int()
+
+type scrapeChannelsKey struct{}
+
+// scrapeChannels returns the channels the scrape the context belongs to
+// refreshes, which are all the channels unless --twitch.scrape-batch-size is
+// set.
+func scrapeChannels(ctx context.Context, channelNames ChannelNames) ChannelNames {
+	if batch, ok := ctx.Value(scrapeChannelsKey{}).(ChannelNames); ok {
+		return batch
+	}
+
+	return channelNames
+}
+
+// rotation picks the batch of channels refreshed by each scrape, and keeps the
+// last metrics of every channel so the ones which are not part of the batch
+// can still be served.
+type rotation struct {
+	channelNames ChannelNames
+	logins       map[string]bool
+
+	mtx     sync.Mutex
+	offset  int
+	metrics map[string]map[string][]prometheus.Metric // collector -> login -> metrics
+}
+
+func newRotation(channelNames ChannelNames) *rotation {
+	logins := make(map[string]bool)
+	for _, n := range channelNames {
+		logins[strings.ToLower(n)] = true
+	}
+
+	return &rotation{
+		channelNames: channelNames,
+		logins:       logins,
+		metrics:      make(map[string]map[string][]prometheus.Metric),
+	}
+}
+
+// enabled reports whether the scrapes only refresh a batch of the channels.
+func (r *rotation) enabled() bool {
+	return *scrapeBatchSize > 0 && *scrapeBatchSize < len(r.channelNames)
+}
+
+// next returns the next batch of channels, wrapping around the channel names.
+func (r *rotation) next() ChannelNames {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	batch := ChannelNames{}
+	for i := 0; i < *scrapeBatchSize; i++ {
+		batch = append(batch, r.channelNames[(r.offset+i)%len(r.channelNames)])
+	}
+
+	r.offset = (r.offset + *scrapeBatchSize) % len(r.channelNames)
+
+	return batch
+}
+
+// forward sends the metrics of a collector to ch, keeping the ones of the
+// configured channels. Once the collector is done, the kept metrics of the
+// channels which were neither refreshed nor part of the batch are sent too.
+func (r *rotation) forward(collector string, batch ChannelNames, metrics <-chan prometheus.Metric, ch chan<- prometheus.Metric) {
+	fresh := make(map[string][]prometheus.Metric)
+
+	for m := range metrics {
+		if login := metricLogin(m); r.logins[login] {
+			fresh[login] = append(fresh[login], m)
+		}
+
+		ch <- m
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	kept, ok := r.metrics[collector]
+	if !ok {
+		kept = make(map[string][]prometheus.Metric)
+		r.metrics[collector] = kept
+	}
+
+	// channels of the batch without metrics no longer have any, such as the
+	// viewers of a channel which went offline
+	for _, n := range batch {
+		delete(kept, strings.ToLower(n))
+	}
+
+	for login, metrics := range kept {
+		if _, ok := fresh[login]; ok {
+			continue
+		}
+
+		for _, m := range metrics {
+			ch <- m
+		}
+	}
+
+	for login, metrics := range fresh {
+		kept[login] = metrics
+	}
+}
+
+// metricLogin returns the value of the login label of the metric, or an empty
+// string when it has none.
+func metricLogin(m prometheus.Metric) string {
+	var metric dto.Metric
+	if err := m.Write(&metric); err != nil {
+		return ""
+	}
+
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == "login" {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
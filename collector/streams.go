@@ -0,0 +1,25 @@
+package collector
+
+import "github.com/nicklaw5/helix/v2"
+
+// getStreams returns the live streams of the given logins, requesting them in
+// batches since Helix caps the page size at 100 streams.
+func getStreams(client *helix.Client, logins []string) ([]helix.Stream, error) {
+	return inBatches(logins, func(batch []string) ([]helix.Stream, error) {
+		streamsResp, err := client.GetStreams(&helix.StreamsParams{
+			UserLogins: batch,
+			First:      len(batch),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if streamsResp.StatusCode != 200 {
+			return nil, helixError(streamsResp.StatusCode, streamsResp.ErrorMessage)
+		}
+
+		recordLive(batch, streamsResp.Data.Streams)
+
+		return streamsResp.Data.Streams, nil
+	})
+}
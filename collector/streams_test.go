@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+)
+
+func TestGetStreamsBatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		channels    int
+		wantBatches []int
+	}{
+		{name: "one batch", channels: 50, wantBatches: []int{50}},
+		{name: "exactly one batch", channels: 100, wantBatches: []int{100}},
+		{name: "150 channels", channels: 150, wantBatches: []int{100, 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logins := []string{}
+			for i := range tt.channels {
+				logins = append(logins, fmt.Sprintf("channel%d", i))
+			}
+
+			mtx := sync.Mutex{}
+			queried := []string{}
+			batches := []int{}
+			handler := testutil.Handler(testutil.DefaultFixtures)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mtx.Lock()
+				batch := r.URL.Query()["user_login"]
+				queried = append(queried, batch...)
+				batches = append(batches, len(batch))
+
+				// every channel of the batch must fit in the page
+				if first, _ := strconv.Atoi(r.URL.Query().Get("first")); first != len(batch) {
+					t.Errorf("first = %d, want the %d channels of the batch", first, len(batch))
+				}
+				mtx.Unlock()
+
+				handler.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			client, err := testutil.NewClient(server)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := getStreams(client, logins); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", batches, tt.wantBatches)
+			}
+
+			if !slices.Equal(queried, logins) {
+				t.Errorf("%d channels queried, want all the %d channels", len(queried), len(logins))
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var teamNames = kingpin.Flag("twitch.team",
+	"Name of a Twitch team to aggregate the live channels of, may be repeated.").Strings()
+
+// teamFollowersCacheTTL is how long the follower count of a member of a team is
+// cached for, since the counts cost a request per member.
+var teamFollowersCacheTTL = kingpin.Flag("cache.team-followers-ttl",
+	"How long the follower count of a member of a team given with --twitch.team is cached for.").
+	Default("1h").Duration()
+
+var (
+	teamMembersCache   = cache.DefaultCache.Named("team_members")
+	teamFollowersCache = cache.DefaultCache.Named("team_followers")
+)
+
+type teamMember struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+}
+
+type teamAggregatesCollector struct {
+	logger   *slog.Logger
+	client   *helix.Client
+	clientID string
+
+	// scopeWarned holds the teams already warned about lacking the scope
+	// to read the followers of their members
+	scopeWarned *sync.Map
+
+	teamChannelsTotal  typedDesc
+	teamChannelsLive   typedDesc
+	teamViewersTotal   typedDesc
+	teamFollowersTotal typedDesc
+}
+
+func init() {
+	registerCollector("team_aggregates", defaultDisabled, priorityNormal, NewTeamAggregatesCollector)
+}
+
+func NewTeamAggregatesCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	// the teams endpoint is not supported by the helix client, so it is
+	// requested directly with the client ID of the token
+	clientID, err := getHelixClientID(client)
+	if err != nil {
+		return nil, err
+	}
+
+	c := teamAggregatesCollector{
+		logger:      logger,
+		client:      client,
+		clientID:    clientID,
+		scopeWarned: &sync.Map{},
+
+		teamChannelsTotal: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "team_channels_total"),
+			"The number of channels member of a team.",
+			[]string{"team"}, nil,
+		), prometheus.GaugeValue},
+		teamChannelsLive: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "team_channels_live"),
+			"The number of live channels member of a team.",
+			[]string{"team"}, nil,
+		), prometheus.GaugeValue},
+		teamViewersTotal: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "team_viewers_total"),
+			"The sum of the viewers of the live channels member of a team.",
+			[]string{"team"}, nil,
+		), prometheus.GaugeValue},
+		teamFollowersTotal: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "team_followers_total"),
+			"The sum of the followers of the channels member of a team.",
+			[]string{"team"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c teamAggregatesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	if len(*teamNames) == 0 {
+		return ErrNoData
+	}
+
+	scopeMissing := false
+	defer func() { setScopeMissing("team_aggregates", scopeMissing) }()
+
+	for _, team := range *teamNames {
+		members, err := c.getTeamMembers(team)
+		if err != nil {
+			logger.Error("Failed to collect team stats from Twitch helix API", "team", team, "err", err)
+			return err
+		}
+
+		logins := make([]string, 0, len(members))
+		for _, member := range members {
+			logins = append(logins, member.UserLogin)
+		}
+
+		// the streams of the members are requested in batches, so a team
+		// costs a request per hundred members rather than one per member
+		streams, err := getStreams(c.client, logins)
+		if err != nil {
+			logger.Error("Failed to collect stream stats from Twitch helix API", "team", team, "err", err)
+			return err
+		}
+
+		viewers := 0
+		for _, s := range streams {
+			viewers += s.ViewerCount
+		}
+
+		ch <- c.teamChannelsTotal.mustNewConstMetric(float64(len(members)), team)
+		ch <- c.teamChannelsLive.mustNewConstMetric(float64(len(streams)), team)
+		ch <- c.teamViewersTotal.mustNewConstMetric(float64(viewers), team)
+
+		followers, ok, err := c.getTeamFollowers(members)
+		if err != nil {
+			logger.Error("Failed to collect follower stats from Twitch helix API", "team", team, "err", err)
+			return err
+		}
+
+		// the followers require a user access token, exporting a partial
+		// sum instead would be misleading
+		if !ok {
+			if _, warned := c.scopeWarned.LoadOrStore(team, true); !warned {
+				logger.Warn("Skipping the followers of a team, the access token may not read the followers of its members", "team", team)
+			}
+
+			scopeMissing = true
+			continue
+		}
+
+		ch <- c.teamFollowersTotal.mustNewConstMetric(float64(followers), team)
+	}
+
+	return nil
+}
+
+// getTeamFollowers sums the followers of the members of a team, the follower
+// count of each member is cached for --cache.team-followers-ttl. It reports
+// false when the token may not read the followers.
+func (c teamAggregatesCollector) getTeamFollowers(members []teamMember) (int, bool, error) {
+	total := 0
+	for _, member := range members {
+		if followers, ok := teamFollowersCache.Get(member.UserID); ok {
+			total += followers.(int)
+			continue
+		}
+
+		followsResp, err := c.client.GetChannelFollows(&helix.GetChannelFollowsParams{
+			BroadcasterID: member.UserID,
+			First:         1,
+		})
+		if err != nil {
+			return 0, false, err
+		}
+
+		if followsResp.StatusCode == http.StatusUnauthorized || followsResp.StatusCode == http.StatusForbidden {
+			return 0, false, nil
+		}
+
+		if followsResp.StatusCode != http.StatusOK {
+			return 0, false, helixError(followsResp.StatusCode, followsResp.ErrorMessage)
+		}
+
+		teamFollowersCache.Set(member.UserID, followsResp.Data.Total, *teamFollowersCacheTTL)
+		total += followsResp.Data.Total
+	}
+
+	return total, true, nil
+}
+
+// getTeamMembers returns the members of a team, from the cache when they were
+// requested within --cache.team-ttl.
+func (c teamAggregatesCollector) getTeamMembers(team string) ([]teamMember, error) {
+	if members, ok := teamMembersCache.Get(team); ok {
+		return members.([]teamMember), nil
+	}
+
+	var teamsResp struct {
+		Data []struct {
+			Users []teamMember `json:"users"`
+		} `json:"data"`
+	}
+
+	if _, err := helixGet(c.client, c.clientID, "/teams", url.Values{"name": {team}}, &teamsResp); err != nil {
+		return nil, err
+	}
+
+	if len(teamsResp.Data) == 0 {
+		return nil, fmt.Errorf("%w: team %s", ErrChannelNotFound, team)
+	}
+
+	teamMembersCache.Set(team, teamsResp.Data[0].Users, *teamsCacheTTL)
+
+	return teamsResp.Data[0].Users, nil
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var defaultCollectorTimeout = kingpin.Flag("collector.timeout",
+	"Maximum duration of the update of a collector on a scrape, overridden by --collector.<name>.timeout. 0 disables the timeout.").
+	Default("0s").Duration()
+
+func collectorTimeoutsDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "collector", "timeout_total"),
+		"Number of updates of a collector which exceeded its timeout.",
+		[]string{"collector"},
+		nil,
+	)
+}
+
+var (
+	collectorTimeouts = make(map[string]*time.Duration) // per collector overrides of --collector.timeout
+
+	collectorTimeoutsTotalMtx = sync.Mutex{}
+	collectorTimeoutsTotal    = make(map[string]int)
+)
+
+// registerCollectorTimeout registers the flag overriding the timeout of a
+// collector.
+func registerCollectorTimeout(collector string) {
+	collectorTimeouts[collector] = kingpin.Flag("collector."+collector+".timeout",
+		"Maximum duration of the update of the "+collector+" collector, overriding --collector.timeout.").
+		Default("0s").Duration()
+}
+
+// collectorTimeout returns the timeout of the update of a collector, or 0 when
+// it has none.
+func collectorTimeout(collector string) time.Duration {
+	if timeout, ok := collectorTimeouts[collector]; ok && *timeout > 0 {
+		return *timeout
+	}
+
+	return *defaultCollectorTimeout
+}
+
+func collectorTimedOut(collector string) {
+	collectorTimeoutsTotalMtx.Lock()
+	defer collectorTimeoutsTotalMtx.Unlock()
+
+	collectorTimeoutsTotal[collector]++
+}
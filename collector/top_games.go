@@ -0,0 +1,218 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/eventsub"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	topGamesLimit = kingpin.Flag("twitch.top-games-limit",
+		"Number of top games to export the streams of, at most 100.").
+		Default("100").Int()
+	topGamesStreamLimit = kingpin.Flag("twitch.top-games-stream-limit",
+		"Number of streams to export for each top game, at most 100.").
+		Default("100").Int()
+	topGamesAggregate = kingpin.Flag("twitch.top-games-aggregate",
+		"Export the total viewers of each top game as twitch_top_game_viewers_total.").
+		Default("false").Bool()
+	topGamesInclude = kingpin.Flag("twitch.top-games-include",
+		"Name or ID of a top game to export the streams of, the other games are skipped. Repeatable.").
+		Strings()
+	topGamesExclude = kingpin.Flag("twitch.top-games-exclude",
+		"Name or ID of a top game to skip. Repeatable.").
+		Strings()
+	topGamesLanguages = kingpin.Flag("twitch.top-games-languages",
+		"Comma separated languages of the streams to export for each top game, eg: en,fr. All the languages are exported when empty. Repeatable.").
+		Strings()
+	topGamesNoBlock = kingpin.Flag("twitch.top-games-no-block",
+		"Stop the top games walk when the rate limit is almost exhausted, rather than waiting for it to reset.").
+		Default("false").Bool()
+)
+
+// topGamesRateLimitFloor is the number of remaining helix requests under which
+// the top games walk waits for the rate limit to reset.
+const topGamesRateLimitFloor = 10
+
+type topGamesCollector struct {
+	logger    *slog.Logger
+	client    *helix.Client
+	languages []string
+
+	topGamesViewersTotal typedDesc
+	topGameViewersTotal  typedDesc
+	topGamesPartial      typedDesc
+}
+
+func init() {
+	// disabled by default since walking the top games costs one request per
+	// game on every scrape
+	registerCollector("top_games", defaultDisabled, priorityLow, NewTopGamesCollector)
+}
+
+func NewTopGamesCollector(logger *slog.Logger, client *helix.Client, eventsubClient *eventsub.Client, channelNames ChannelNames) (Collector, error) {
+	if *topGamesLimit < 1 || *topGamesLimit > 100 {
+		return nil, errors.New("top games limit must be between 1 and 100")
+	}
+
+	if *topGamesStreamLimit < 1 || *topGamesStreamLimit > 100 {
+		return nil, errors.New("top games stream limit must be between 1 and 100")
+	}
+
+	languages := topGameLanguages()
+	if len(languages) > 100 {
+		return nil, errors.New("top games languages must be at most 100")
+	}
+
+	c := topGamesCollector{
+		logger:    logger,
+		client:    client,
+		languages: languages,
+
+		topGamesViewersTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "top_games_viewers_total"),
+			"How many viewers are watching the top streams of the top games.",
+			[]string{"username", "login", "game"}, nil,
+		), prometheus.GaugeValue},
+		topGameViewersTotal: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "top_game_viewers_total"),
+			"How many viewers are watching the top streams of a top game.",
+			[]string{"game"}, nil,
+		), prometheus.GaugeValue},
+		topGamesPartial: typedDesc{newDesc(
+			prometheus.BuildFQName(namespace, "", "top_games_partial"),
+			"Whether the top games walk stopped early, by reason.",
+			[]string{"reason"}, nil,
+		), prometheus.GaugeValue},
+	}
+
+	return c, nil
+}
+
+func (c topGamesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	logger := scrapeLogger(ctx, c.logger)
+
+	topGamesResp, err := c.client.GetTopGames(&helix.TopGamesParams{
+		First: *topGamesLimit,
+	})
+
+	if err != nil {
+		logger.Error("Failed to collect top games from Twitch helix API", "err", err)
+		return err
+	}
+
+	if topGamesResp.StatusCode != 200 {
+		logger.Error("Failed to collect top games from Twitch helix API", "err", topGamesResp.ErrorMessage)
+		return helixError(topGamesResp.StatusCode, topGamesResp.ErrorMessage)
+	}
+
+	partial := 0.0
+	defer func() {
+		ch <- c.topGamesPartial.mustNewConstMetric(partial, "rate_limit")
+	}()
+
+	for i, game := range topGamesResp.Data.Games {
+		if !topGameWanted(game) {
+			continue
+		}
+
+		// the scrape gave up on the collector, such as when it timed out
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		streamsResp, err := c.client.GetStreams(&helix.StreamsParams{
+			GameIDs:  []string{game.ID},
+			First:    *topGamesStreamLimit,
+			Language: c.languages,
+		})
+
+		if err != nil {
+			logger.Error("Failed to collect top game streams from Twitch helix API", "game", game.Name, "err", err)
+			return err
+		}
+
+		if streamsResp.StatusCode != 200 {
+			logger.Error("Failed to collect top game streams from Twitch helix API", "game", game.Name, "err", streamsResp.ErrorMessage)
+			return helixError(streamsResp.StatusCode, streamsResp.ErrorMessage)
+		}
+
+		gameViewers := 0
+		for _, s := range streamsResp.Data.Streams {
+			ch <- c.topGamesViewersTotal.mustNewConstMetric(float64(s.ViewerCount), s.UserName, s.UserLogin, s.GameName)
+			gameViewers += s.ViewerCount
+		}
+
+		if *topGamesAggregate {
+			ch <- c.topGameViewersTotal.mustNewConstMetric(float64(gameViewers), game.Name)
+		}
+
+		// wait for the rate limit to reset rather than failing the requests of
+		// the remaining games, unless the walk should not block the scrape
+		if streamsResp.GetRateLimit() > 0 && streamsResp.GetRateLimitRemaining() < topGamesRateLimitFloor {
+			if i == len(topGamesResp.Data.Games)-1 {
+				break
+			}
+
+			if *topGamesNoBlock {
+				logger.Warn("rate limit almost exhausted, stopping the top games walk", "remaining", streamsResp.GetRateLimitRemaining(), "games", i+1, "total", len(topGamesResp.Data.Games))
+				partial = 1
+				return nil
+			}
+
+			wait := time.Until(time.Unix(int64(streamsResp.GetRateLimitReset()), 0))
+			logger.Warn("rate limit almost exhausted, waiting for it to reset", "remaining", streamsResp.GetRateLimitRemaining(), "wait", wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return nil
+}
+
+// topGameLanguages returns the languages of --twitch.top-games-languages, which
+// may be given as comma separated lists.
+func topGameLanguages() []string {
+	languages := []string{}
+	for _, list := range *topGamesLanguages {
+		for _, language := range strings.Split(list, ",") {
+			if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+				languages = append(languages, language)
+			}
+		}
+	}
+
+	return languages
+}
+
+// topGameWanted reports whether the streams of the game should be exported,
+// according to --twitch.top-games-include and --twitch.top-games-exclude. A
+// game which is both included and excluded is included.
+func topGameWanted(game helix.Game) bool {
+	matches := func(games []string) bool {
+		return slices.ContainsFunc(games, func(g string) bool {
+			return g == game.ID || strings.EqualFold(g, game.Name)
+		})
+	}
+
+	if matches(*topGamesInclude) {
+		return true
+	}
+
+	if len(*topGamesInclude) > 0 {
+		return false
+	}
+
+	return !matches(*topGamesExclude)
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestTopGamesLanguages(t *testing.T) {
+	tests := []struct {
+		name      string
+		languages []string
+		want      []string
+	}{
+		{name: "all languages", want: nil},
+		{name: "comma separated", languages: []string{"en,fr"}, want: []string{"en", "fr"}},
+		{name: "repeated", languages: []string{"EN", " de "}, want: []string{"en", "de"}},
+	}
+
+	fixtures := maps.Clone(testutil.DefaultFixtures)
+	fixtures["/games/top"] = `{"data":[{"id":"509658","name":"Just Chatting","box_art_url":""}],"pagination":{}}`
+
+	limit, streamLimit, languages := *topGamesLimit, *topGamesStreamLimit, *topGamesLanguages
+	defer func() {
+		*topGamesLimit, *topGamesStreamLimit, *topGamesLanguages = limit, streamLimit, languages
+	}()
+	*topGamesLimit, *topGamesStreamLimit = 100, 100
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*topGamesLanguages = tt.languages
+
+			var got []string
+			handler := testutil.Handler(fixtures)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/streams" {
+					got = r.URL.Query()["language"]
+				}
+				handler.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			client, err := testutil.NewClient(server)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := NewTopGamesCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), client, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ch := make(chan prometheus.Metric, 10)
+			if err := c.Update(context.Background(), ch); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("languages requested = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopGameWanted(t *testing.T) {
+	justChatting := helix.Game{ID: "509658", Name: "Just Chatting"}
+	fortnite := helix.Game{ID: "33214", Name: "Fortnite"}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		game    helix.Game
+		want    bool
+	}{
+		{name: "no filter", game: justChatting, want: true},
+		{name: "included by name", include: []string{"just chatting"}, game: justChatting, want: true},
+		{name: "included by ID", include: []string{"509658"}, game: justChatting, want: true},
+		{name: "not included", include: []string{"Just Chatting"}, game: fortnite, want: false},
+		{name: "excluded by name", exclude: []string{"Fortnite"}, game: fortnite, want: false},
+		{name: "excluded by ID", exclude: []string{"33214"}, game: fortnite, want: false},
+		{name: "not excluded", exclude: []string{"Fortnite"}, game: justChatting, want: true},
+		{name: "include takes precedence", include: []string{"Fortnite"}, exclude: []string{"33214"}, game: fortnite, want: true},
+		{name: "include takes precedence over an unrelated exclude", include: []string{"Fortnite"}, exclude: []string{"Just Chatting"}, game: justChatting, want: false},
+	}
+
+	include, exclude := *topGamesInclude, *topGamesExclude
+	defer func() { *topGamesInclude, *topGamesExclude = include, exclude }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*topGamesInclude, *topGamesExclude = tt.include, tt.exclude
+
+			if got := topGameWanted(tt.game); got != tt.want {
+				t.Errorf("topGameWanted(%s) = %t, want %t", tt.game.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopGamesSkipsExcludedGames(t *testing.T) {
+	fixtures := maps.Clone(testutil.DefaultFixtures)
+	fixtures["/games/top"] = `{"data":[{"id":"509658","name":"Just Chatting","box_art_url":""},{"id":"33214","name":"Fortnite","box_art_url":""}],"pagination":{}}`
+
+	limit, streamLimit, exclude := *topGamesLimit, *topGamesStreamLimit, *topGamesExclude
+	defer func() { *topGamesLimit, *topGamesStreamLimit, *topGamesExclude = limit, streamLimit, exclude }()
+	*topGamesLimit, *topGamesStreamLimit, *topGamesExclude = 100, 100, []string{"Fortnite"}
+
+	var games []string
+	handler := testutil.Handler(fixtures)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/streams" {
+			games = append(games, r.URL.Query()["game_id"]...)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewTopGamesCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), client, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Update(context.Background(), make(chan prometheus.Metric, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the streams of the excluded game are not requested at all
+	if !slices.Equal(games, []string{"509658"}) {
+		t.Errorf("streams requested for the games %v, want only Just Chatting", games)
+	}
+}
@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/damoun/twitch_exporter/internal/cache"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// userCacheTTL is how long a resolved user is cached for. Users rarely change,
+// but a renamed channel won't be picked up until its entry expires.
+var userCacheTTL = kingpin.Flag("cache.user-ttl",
+	"How long resolved users are cached for. A renamed channel is not picked up until this expires.").
+	Default("24h").Duration()
+
+// userNotFoundCacheTTL is how long a login which does not exist is cached for,
+// so a misspelled channel doesn't cost a request on every scrape.
+var userNotFoundCacheTTL = kingpin.Flag("cache.user-not-found-ttl",
+	"How long a channel which does not exist is remembered for, before it is requested again.").
+	Default("5m").Duration()
+
+var userCache = cache.DefaultCache.Named("user")
+
+// userNotFound is cached in place of a user when the login does not exist, to
+// tell it apart from a login which is not cached.
+type userNotFound struct{}
+
+// getUsersByUsernames resolves the users of the given logins. Users are cached
+// individually, and the ones which are not cached are requested in batches of
+// 100 logins rather than one call per login.
+func getUsersByUsernames(client *helix.Client, logins []string) ([]helix.User, error) {
+	users := []helix.User{}
+	missing := []string{}
+
+	for _, login := range logins {
+		if user, ok := userCache.Get(strings.ToLower(login)); ok {
+			if user, found := user.(helix.User); found {
+				users = append(users, user)
+			}
+
+			continue
+		}
+
+		missing = append(missing, login)
+	}
+
+	if len(missing) == 0 {
+		return users, nil
+	}
+
+	missingUsers, err := inBatches(missing, func(batch []string) ([]helix.User, error) {
+		usersResp, err := client.GetUsers(&helix.UsersParams{
+			Logins: batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if usersResp.StatusCode != 200 {
+			return nil, helixError(usersResp.StatusCode, usersResp.ErrorMessage)
+		}
+
+		return usersResp.Data.Users, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	for _, user := range missingUsers {
+		userCache.Set(strings.ToLower(user.Login), user, *userCacheTTL)
+		found[strings.ToLower(user.Login)] = true
+		users = append(users, user)
+	}
+
+	for _, login := range missing {
+		if !found[strings.ToLower(login)] {
+			userCache.Set(strings.ToLower(login), userNotFound{}, *userNotFoundCacheTTL)
+		}
+	}
+
+	return users, nil
+}
+
+// getBroadcasterIDs resolves the user IDs of the given channels, which are
+// needed to subscribe to eventsub events.
+func getBroadcasterIDs(client *helix.Client, channelNames ChannelNames) ([]string, error) {
+	users, err := getUsersByUsernames(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcasterIDs := []string{}
+	for _, user := range users {
+		broadcasterIDs = append(broadcasterIDs, user.ID)
+	}
+
+	return broadcasterIDs, nil
+}
+
+// getDisplayNames resolves the display names of the given logins, keyed by the
+// lowercase login. It is used by the collectors which only know the login of a
+// channel to label their metrics like the others.
+func getDisplayNames(client *helix.Client, logins []string) (map[string]string, error) {
+	users, err := getUsersByUsernames(client, logins)
+	if err != nil {
+		return nil, err
+	}
+
+	displayNames := make(map[string]string)
+	for _, user := range users {
+		displayNames[strings.ToLower(user.Login)] = user.DisplayName
+	}
+
+	return displayNames, nil
+}
+
+// WarmCache resolves the users of the channels, so the first scrape finds them
+// cached. It returns the logins of the channels which do not exist.
+func WarmCache(client *helix.Client, channelNames ChannelNames) ([]string, error) {
+	users, err := getUsersByUsernames(client, channelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	for _, user := range users {
+		found[strings.ToLower(user.Login)] = true
+	}
+
+	notFound := []string{}
+	for _, n := range channelNames {
+		if !found[strings.ToLower(n)] {
+			notFound = append(notFound, n)
+		}
+	}
+
+	return notFound, nil
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/damoun/twitch_exporter/internal/testutil"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// newCountingClient creates a client of a fake Helix API serving the default
+// fixtures, counting the requests it receives.
+func newCountingClient(t *testing.T) (*helix.Client, *atomic.Int32) {
+	t.Helper()
+
+	requests := &atomic.Int32{}
+	handler := testutil.Handler(testutil.DefaultFixtures)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := testutil.NewClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, requests
+}
+
+// forgetUsers removes the users of the logins from the cache, so the tests do
+// not share cached users.
+func forgetUsers(t *testing.T, logins ...string) {
+	t.Cleanup(func() {
+		for _, login := range logins {
+			userCache.Delete(login)
+		}
+	})
+}
+
+func TestGetUsersByUsernamesExpiration(t *testing.T) {
+	userTTL, notFoundTTL := *userCacheTTL, *userNotFoundCacheTTL
+	*userCacheTTL, *userNotFoundCacheTTL = time.Hour, time.Minute
+	defer func() { *userCacheTTL, *userNotFoundCacheTTL = userTTL, notFoundTTL }()
+
+	client, _ := newCountingClient(t)
+	forgetUsers(t, "somechannel", "missingchannel")
+
+	before := time.Now()
+	if _, err := getUsersByUsernames(client, []string{"somechannel", "missingchannel"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		login string
+		ttl   time.Duration
+	}{
+		{login: "somechannel", ttl: time.Hour},
+		{login: "missingchannel", ttl: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.login, func(t *testing.T) {
+			_, expiresAt, ok := userCache.Peek(tt.login)
+			if !ok {
+				t.Fatalf("%s is not cached", tt.login)
+			}
+
+			if expiresAt.Before(before.Add(tt.ttl)) || expiresAt.After(time.Now().Add(tt.ttl)) {
+				t.Errorf("%s expires at %s, want %s after it was cached", tt.login, expiresAt, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestGetUsersByUsernamesExpired(t *testing.T) {
+	previous := *userCacheTTL
+	*userCacheTTL = time.Millisecond
+	defer func() { *userCacheTTL = previous }()
+
+	client, requests := newCountingClient(t)
+	forgetUsers(t, "somechannel")
+
+	for range 2 {
+		if _, err := getUsersByUsernames(client, []string{"somechannel"}); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("API requested %d times, want 2 since the cached user expired", n)
+	}
+}
+
+func TestGetUsersByUsernamesCached(t *testing.T) {
+	previous := *userCacheTTL
+	*userCacheTTL = time.Hour
+	defer func() { *userCacheTTL = previous }()
+
+	client, requests := newCountingClient(t)
+	forgetUsers(t, "somechannel")
+
+	for range 2 {
+		users, err := getUsersByUsernames(client, []string{"SomeChannel"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 1 || users[0].Login != "somechannel" {
+			t.Errorf("users = %v, want somechannel", users)
+		}
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("API requested %d times, want 1", n)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 Damien PLÉNARD.
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/damoun/twitch_exporter/collector"
+)
+
+// debugChannelsHandler dumps the state of every configured channel as JSON,
+// to troubleshoot why a channel has no metrics.
+func debugChannelsHandler(exporter *collector.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(exporter.DebugChannels()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
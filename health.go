@@ -0,0 +1,76 @@
+// Copyright 2020 Damien PLÉNARD.
+// Licensed under the MIT License
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/damoun/twitch_exporter/collector"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// readinessCacheTTL is how long the result of a readiness check is reused for,
+// so frequent probes don't each validate the tokens against Twitch.
+const readinessCacheTTL = 30 * time.Second
+
+// readinessHandler reports whether the access tokens of the clients are valid,
+// which also requires Twitch to be reachable.
+type readinessHandler struct {
+	clients collector.Clients
+
+	mtx       sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+func newReadinessHandler(clients collector.Clients) *readinessHandler {
+	return &readinessHandler{clients: clients}
+}
+
+func (h *readinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.check(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// check validates the tokens, reusing the previous result when it is more
+// recent than readinessCacheTTL.
+func (h *readinessHandler) check() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if time.Since(h.checkedAt) < readinessCacheTTL {
+		return h.err
+	}
+
+	h.err = nil
+	if err := validateToken(h.clients.App, h.clients.App.GetAppAccessToken()); err != nil {
+		h.err = err
+	} else if h.clients.User != nil {
+		h.err = validateToken(h.clients.User, h.clients.User.GetUserAccessToken())
+	}
+
+	h.checkedAt = time.Now()
+
+	return h.err
+}
+
+func validateToken(client *helix.Client, token string) error {
+	valid, _, err := client.ValidateToken(token)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return errors.New("invalid access token")
+	}
+
+	return nil
+}
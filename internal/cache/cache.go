@@ -0,0 +1,158 @@
+// Package cache provides a small in-memory cache with per entry expiration,
+// used to avoid requesting rarely changing data from the Twitch API on every
+// scrape.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCache is the cache shared by the collectors.
+var DefaultCache = New()
+
+type entry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// Cache is a concurrency safe key/value store where every entry expires after
+// the duration it was set with.
+type Cache struct {
+	mtx     sync.Mutex
+	entries map[string]entry
+	stats   map[string]*Stats
+}
+
+// Stats counts the lookups of a named view of the cache.
+type Stats struct {
+	Hits   int
+	Misses int
+}
+
+// New creates an empty Cache.
+func New() *Cache {
+	return &Cache{
+		entries: make(map[string]entry),
+		stats:   make(map[string]*Stats),
+	}
+}
+
+// Get returns the value stored under key, and whether it was found and has not
+// expired yet.
+func (c *Cache) Get(key string) (any, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// Peek returns the value stored under key and when it expires, and whether it
+// was found and has not expired yet. Unlike Get, an expired entry is left in
+// place.
+func (c *Cache) Peek(key string) (any, time.Time, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, time.Time{}, false
+	}
+
+	return e.value, e.expiresAt, true
+}
+
+// Set stores value under key for the duration of ttl.
+func (c *Cache) Set(key string, value any, ttl time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.entries[key] = entry{
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	}
+}
+
+// Delete removes the value stored under key.
+func (c *Cache) Delete(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Stats returns a copy of the hit and miss counts of every named view of the
+// cache.
+func (c *Cache) Stats() map[string]Stats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	stats := make(map[string]Stats, len(c.stats))
+	for name, s := range c.stats {
+		stats[name] = *s
+	}
+
+	return stats
+}
+
+// Named returns a view of the cache for a single type of entity, whose keys are
+// namespaced by name and whose lookups are counted in the cache stats.
+func (c *Cache) Named(name string) *Named {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, ok := c.stats[name]; !ok {
+		c.stats[name] = &Stats{}
+	}
+
+	return &Named{cache: c, name: name}
+}
+
+// Named is a view of a Cache for a single type of entity.
+type Named struct {
+	cache *Cache
+	name  string
+}
+
+// Get returns the value stored under key, recording the lookup as a hit or a
+// miss.
+func (n *Named) Get(key string) (any, bool) {
+	value, ok := n.cache.Get(n.name + ":" + key)
+
+	n.cache.mtx.Lock()
+	defer n.cache.mtx.Unlock()
+
+	if ok {
+		n.cache.stats[n.name].Hits++
+	} else {
+		n.cache.stats[n.name].Misses++
+	}
+
+	return value, ok
+}
+
+// Peek returns the value stored under key and when it expires, without
+// counting the lookup in the cache stats.
+func (n *Named) Peek(key string) (any, time.Time, bool) {
+	return n.cache.Peek(n.name + ":" + key)
+}
+
+// Set stores value under key for the duration of ttl.
+func (n *Named) Set(key string, value any, ttl time.Duration) {
+	n.cache.Set(n.name+":"+key, value, ttl)
+}
+
+// Delete removes the value stored under key.
+func (n *Named) Delete(key string) {
+	n.cache.Delete(n.name + ":" + key)
+}
@@ -0,0 +1,174 @@
+package eventsub
+
+import "github.com/nicklaw5/helix/v2"
+
+// HypeTrainContribution is a contribution made towards a hype train.
+type HypeTrainContribution struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+	Type      string `json:"type"`
+	Total     int    `json:"total"`
+}
+
+// HypeTrainEvent is the payload of the channel.hype_train.begin and
+// channel.hype_train.progress events.
+type HypeTrainEvent struct {
+	ID                   string                  `json:"id"`
+	BroadcasterUserID    string                  `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string                  `json:"broadcaster_user_login"`
+	BroadcasterUserName  string                  `json:"broadcaster_user_name"`
+	Total                int                     `json:"total"`
+	Progress             int                     `json:"progress"`
+	Goal                 int                     `json:"goal"`
+	Level                int                     `json:"level"`
+	TopContributions     []HypeTrainContribution `json:"top_contributions"`
+	LastContribution     HypeTrainContribution   `json:"last_contribution"`
+	StartedAt            helix.Time              `json:"started_at"`
+	ExpiresAt            helix.Time              `json:"expires_at"`
+}
+
+// HypeTrainEndEvent is the payload of the channel.hype_train.end event.
+type HypeTrainEndEvent struct {
+	ID                   string                  `json:"id"`
+	BroadcasterUserID    string                  `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string                  `json:"broadcaster_user_login"`
+	BroadcasterUserName  string                  `json:"broadcaster_user_name"`
+	Total                int                     `json:"total"`
+	Level                int                     `json:"level"`
+	TopContributions     []HypeTrainContribution `json:"top_contributions"`
+	StartedAt            helix.Time              `json:"started_at"`
+	EndedAt              helix.Time              `json:"ended_at"`
+	CooldownEndsAt       helix.Time              `json:"cooldown_ends_at"`
+}
+
+// ChannelRaidEvent is the payload of the channel.raid event.
+type ChannelRaidEvent struct {
+	FromBroadcasterUserID    string `json:"from_broadcaster_user_id"`
+	FromBroadcasterUserLogin string `json:"from_broadcaster_user_login"`
+	FromBroadcasterUserName  string `json:"from_broadcaster_user_name"`
+	ToBroadcasterUserID      string `json:"to_broadcaster_user_id"`
+	ToBroadcasterUserLogin   string `json:"to_broadcaster_user_login"`
+	ToBroadcasterUserName    string `json:"to_broadcaster_user_name"`
+	Viewers                  int    `json:"viewers"`
+}
+
+// ChannelBanEvent is the payload of the channel.ban event. Timeouts are bans
+// which are not permanent and have an EndsAt time.
+type ChannelBanEvent struct {
+	UserID               string     `json:"user_id"`
+	UserLogin            string     `json:"user_login"`
+	UserName             string     `json:"user_name"`
+	BroadcasterUserID    string     `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string     `json:"broadcaster_user_login"`
+	BroadcasterUserName  string     `json:"broadcaster_user_name"`
+	ModeratorUserID      string     `json:"moderator_user_id"`
+	ModeratorUserLogin   string     `json:"moderator_user_login"`
+	ModeratorUserName    string     `json:"moderator_user_name"`
+	Reason               string     `json:"reason"`
+	BannedAt             helix.Time `json:"banned_at"`
+	EndsAt               helix.Time `json:"ends_at"`
+	IsPermanent          bool       `json:"is_permanent"`
+}
+
+// ChannelUnbanEvent is the payload of the channel.unban event.
+type ChannelUnbanEvent struct {
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	ModeratorUserID      string `json:"moderator_user_id"`
+	ModeratorUserLogin   string `json:"moderator_user_login"`
+	ModeratorUserName    string `json:"moderator_user_name"`
+}
+
+// PollChoice is a choice of a poll, votes are only set on progress and end events.
+type PollChoice struct {
+	ID                 string `json:"id"`
+	Title              string `json:"title"`
+	BitsVotes          int    `json:"bits_votes"`
+	ChannelPointsVotes int    `json:"channel_points_votes"`
+	Votes              int    `json:"votes"`
+}
+
+// ChannelPollEvent is the payload of the channel.poll.begin, channel.poll.progress
+// and channel.poll.end events. Status and EndedAt are only set on end events.
+type ChannelPollEvent struct {
+	ID                   string       `json:"id"`
+	BroadcasterUserID    string       `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string       `json:"broadcaster_user_login"`
+	BroadcasterUserName  string       `json:"broadcaster_user_name"`
+	Title                string       `json:"title"`
+	Choices              []PollChoice `json:"choices"`
+	Status               string       `json:"status"`
+	StartedAt            helix.Time   `json:"started_at"`
+	EndsAt               helix.Time   `json:"ends_at"`
+	EndedAt              helix.Time   `json:"ended_at"`
+}
+
+// PredictionOutcome is an outcome of a prediction, users and channel points
+// are only set on progress, lock and end events.
+type PredictionOutcome struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Color         string `json:"color"`
+	Users         int    `json:"users"`
+	ChannelPoints int    `json:"channel_points"`
+}
+
+// ChannelPredictionEvent is the payload of the channel.prediction.begin,
+// channel.prediction.progress, channel.prediction.lock and channel.prediction.end
+// events. WinningOutcomeID, Status and EndedAt are only set on end events.
+type ChannelPredictionEvent struct {
+	ID                   string              `json:"id"`
+	BroadcasterUserID    string              `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string              `json:"broadcaster_user_login"`
+	BroadcasterUserName  string              `json:"broadcaster_user_name"`
+	Title                string              `json:"title"`
+	WinningOutcomeID     string              `json:"winning_outcome_id"`
+	Outcomes             []PredictionOutcome `json:"outcomes"`
+	Status               string              `json:"status"`
+	StartedAt            helix.Time          `json:"started_at"`
+	LocksAt              helix.Time          `json:"locks_at"`
+	LockedAt             helix.Time          `json:"locked_at"`
+	EndedAt              helix.Time          `json:"ended_at"`
+}
+
+// ChannelAdBreakBeginEvent is the payload of the channel.ad_break.begin event.
+type ChannelAdBreakBeginEvent struct {
+	DurationSeconds      int        `json:"duration_seconds"`
+	StartedAt            helix.Time `json:"started_at"`
+	IsAutomatic          bool       `json:"is_automatic"`
+	BroadcasterUserID    string     `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string     `json:"broadcaster_user_login"`
+	BroadcasterUserName  string     `json:"broadcaster_user_name"`
+	RequesterUserID      string     `json:"requester_user_id"`
+	RequesterUserLogin   string     `json:"requester_user_login"`
+	RequesterUserName    string     `json:"requester_user_name"`
+}
+
+// ChannelUpdateEvent is the payload of version 2 of the channel.update event.
+type ChannelUpdateEvent struct {
+	BroadcasterUserID           string   `json:"broadcaster_user_id"`
+	BroadcasterUserLogin        string   `json:"broadcaster_user_login"`
+	BroadcasterUserName         string   `json:"broadcaster_user_name"`
+	Title                       string   `json:"title"`
+	Language                    string   `json:"language"`
+	CategoryID                  string   `json:"category_id"`
+	CategoryName                string   `json:"category_name"`
+	ContentClassificationLabels []string `json:"content_classification_labels"`
+}
+
+// ChannelModerateEvent is the payload of version 2 of the channel.moderate
+// event. Only the action is decoded, the details of each action are left out.
+type ChannelModerateEvent struct {
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	ModeratorUserID      string `json:"moderator_user_id"`
+	ModeratorUserLogin   string `json:"moderator_user_login"`
+	ModeratorUserName    string `json:"moderator_user_name"`
+	Action               string `json:"action"`
+}
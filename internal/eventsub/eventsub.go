@@ -1,10 +1,15 @@
 package eventsub
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/LinneB/twitchwh"
 	"github.com/nicklaw5/helix/v2"
@@ -24,25 +29,48 @@ type ChannelChatMessageEvent struct {
 	SourceMessageID         string `json:"source_message_id"`
 	IsSourceOnly            bool   `json:"is_source_only"`
 	Message                 struct {
-		Text      string `json:"text"`
-		Fragments []struct {
-			Type      string      `json:"type"`
-			Text      string      `json:"text"`
-			Cheermote interface{} `json:"cheermote"`
-			Emote     interface{} `json:"emote"`
-			Mention   interface{} `json:"mention"`
-		} `json:"fragments"`
+		Text      string     `json:"text"`
+		Fragments []Fragment `json:"fragments"`
 	} `json:"message"`
 	Color                       string  `json:"color"`
 	Badges                      []Badge `json:"badges"`
 	SourceBadges                []Badge `json:"source_badges"`
 	MessageType                 string  `json:"message_type"`
-	Cheer                       string  `json:"cheer"`
+	Cheer                       *Cheer  `json:"cheer"`
 	Reply                       string  `json:"reply"`
 	ChannelPointsCustomRewardID string  `json:"channel_points_custom_reward_id"`
 	ChannelPointsAnimationID    string  `json:"channel_points_animation_id"`
 }
 
+type Fragment struct {
+	Type      string      `json:"type"`
+	Text      string      `json:"text"`
+	Cheermote *Cheermote  `json:"cheermote"`
+	Emote     *Emote      `json:"emote"`
+	Mention   interface{} `json:"mention"`
+}
+
+// Cheer is set on chat messages which cheered bits, with the total amount of
+// bits of the message.
+type Cheer struct {
+	Bits int `json:"bits"`
+}
+
+// Emote is set on the emote fragments of a chat message.
+type Emote struct {
+	ID         string   `json:"id"`
+	EmoteSetID string   `json:"emote_set_id"`
+	OwnerID    string   `json:"owner_id"`
+	Format     []string `json:"format"`
+}
+
+// Cheermote is set on the cheermote fragments of a chat message.
+type Cheermote struct {
+	Prefix string `json:"prefix"`
+	Bits   int    `json:"bits"`
+	Tier   int    `json:"tier"`
+}
+
 type Badge struct {
 	SetID string `json:"set_id"`
 	ID    string `json:"id"`
@@ -51,13 +79,51 @@ type Badge struct {
 
 var ErrEventsubClientNotSet = errors.New("eventsub client not set")
 
+// ErrSubscriptionNotRegistered is returned when resubscribing to a
+// subscription which was not created by the exporter.
+var ErrSubscriptionNotRegistered = errors.New("eventsub subscription not registered")
+
+// subscriptionKey identifies a subscription independently of its ID, which
+// changes when it is recreated.
+type subscriptionKey struct {
+	eventType string
+	version   string
+	condition helix.EventSubCondition
+}
+
 type Client struct {
 	webhookURL    string
 	webhookSecret string
 
+	// appClient creates the subscriptions, it is the user client for the
+	// websocket transport, which Twitch requires a user access token for
 	appClient *helix.Client
 	logger    *slog.Logger
 	cl        *twitchwh.Client
+
+	// websocket is set for the websocket transport, the events are then
+	// received on the websocket session rather than by the webhook client
+	websocket  bool
+	sessionMtx sync.Mutex
+	sessionID  string
+	connected  atomic.Bool
+	reconnects atomic.Int64
+
+	sessionStartMtx sync.Mutex
+	sessionStarted  bool
+
+	callbacksMtx sync.Mutex
+	callbacks    map[string][]func(eventRaw json.RawMessage)
+
+	// registered holds the user ID of every subscription the collectors
+	// subscribed to, so they can be recreated
+	registeredMtx sync.Mutex
+	registered    map[subscriptionKey]string
+
+	// challenges holds a channel per subscription ID the self test waits for
+	// the verification challenge of
+	challengesMtx sync.Mutex
+	challenges    map[string]chan struct{}
 }
 
 func New(
@@ -70,6 +136,9 @@ func New(
 		logger:        logger,
 		webhookURL:    webhookURL,
 		webhookSecret: webhookSecret,
+		callbacks:     make(map[string][]func(eventRaw json.RawMessage)),
+		registered:    make(map[subscriptionKey]string),
+		challenges:    make(map[string]chan struct{}),
 	}
 
 	cl, err := twitchwh.New(twitchwh.ClientConfig{
@@ -92,60 +161,129 @@ func New(
 func (c *Client) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		c.logger.Debug("received event", "body", r.Body, "headers", r.Header)
+		if r.Header.Get("Twitch-Eventsub-Message-Type") == "webhook_callback_verification" {
+			c.challengeReceived(r)
+		}
 		c.cl.Handler(w, r)
 		c.logger.Debug("event handled", "headers", w.Header())
 	}
 }
 
+// On registers a callback for an event type. Several collectors may listen to
+// the same event type, so every callback registered for it is called, whereas
+// the webhook client only keeps the last one.
 func (c *Client) On(event string, callback func(eventRaw json.RawMessage)) error {
 	// juuust in case
-	if c.cl == nil {
+	if c.cl == nil && !c.websocket {
 		c.logger.Warn("eventsub client not set")
 		return ErrEventsubClientNotSet
 	}
 
-	c.cl.On(event, callback)
+	c.callbacksMtx.Lock()
+	defer c.callbacksMtx.Unlock()
+
+	if _, ok := c.callbacks[event]; !ok && c.cl != nil {
+		c.cl.On(event, func(eventRaw json.RawMessage) {
+			c.dispatch(event, eventRaw)
+		})
+	}
+
+	c.callbacks[event] = append(c.callbacks[event], callback)
 	return nil
 }
 
+// dispatch calls every callback registered for the event type.
+func (c *Client) dispatch(event string, eventRaw json.RawMessage) {
+	c.callbacksMtx.Lock()
+	callbacks := c.callbacks[event]
+	c.callbacksMtx.Unlock()
+
+	for _, callback := range callbacks {
+		callback(eventRaw)
+	}
+}
+
+// transport returns the transport the subscriptions are created with.
+func (c *Client) transport() helix.EventSubTransport {
+	if c.websocket {
+		c.sessionMtx.Lock()
+		defer c.sessionMtx.Unlock()
+
+		return helix.EventSubTransport{
+			Method:    "websocket",
+			SessionID: c.sessionID,
+		}
+	}
+
+	return helix.EventSubTransport{
+		Method:   "webhook",
+		Callback: c.webhookURL,
+		Secret:   c.webhookSecret,
+	}
+}
+
+// Subscribe subscribes to an event of a broadcaster, using the broadcaster as
+// both the user and the broadcaster of the condition. This is what the chat
+// events expect, since the access token is for the broadcaster.
 func (c *Client) Subscribe(eventType string, broadcasterID string) error {
-	if c.cl == nil {
+	return c.SubscribeWithCondition(eventType, "1", broadcasterID, helix.EventSubCondition{
+		UserID:            broadcasterID,
+		BroadcasterUserID: broadcasterID,
+	})
+}
+
+// SubscribeWithCondition subscribes to a version of an event with the given
+// condition. userID is the user the condition refers to, and is used to look up
+// existing subscriptions so they are not created twice.
+func (c *Client) SubscribeWithCondition(eventType, version, userID string, condition helix.EventSubCondition) error {
+	if c.cl == nil && !c.websocket {
 		c.logger.Warn("eventsub client not set")
 		return ErrEventsubClientNotSet
 	}
 
-	c.logger.Info("subscribing to event", "event", eventType, "broadcaster_id", broadcasterID)
+	// the subscription must be created for a session, which is started by
+	// the first one
+	if c.websocket {
+		if err := c.startSession(); err != nil {
+			return errors.Join(errors.New("failed to start the eventsub websocket session"), err)
+		}
+	}
+
+	c.logger.Info("subscribing to event", "event", eventType, "user_id", userID)
+
+	c.registeredMtx.Lock()
+	c.registered[subscriptionKey{eventType: eventType, version: version, condition: condition}] = userID
+	c.registeredMtx.Unlock()
 
 	// cannot filter by both the user id and the event type, so the better option is to get all the user
 	// subscriptions and see if the event type is found already
 	subscriptions, err := c.appClient.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{
-		UserID: broadcasterID,
+		UserID: userID,
 	})
 
 	if err != nil {
 		return err
 	}
 
+	transport := c.transport()
 	for _, v := range subscriptions.Data.EventSubSubscriptions {
-		if v.Type == eventType && (v.Status == "enabled" || v.Status == "webhook_callback_verification_pending") {
-			c.logger.Info("subscription already exists", "event", eventType, "broadcaster_id", broadcasterID)
+		// the subscriptions of a previous websocket session are not delivered
+		// to the current one
+		if v.Transport.Method != transport.Method || v.Transport.SessionID != transport.SessionID {
+			continue
+		}
+
+		if v.Type == eventType && v.Version == version && v.Condition == condition && (v.Status == "enabled" || v.Status == "webhook_callback_verification_pending") {
+			c.logger.Info("subscription already exists", "event", eventType, "user_id", userID)
 			return nil
 		}
 	}
 
 	res, err := c.appClient.CreateEventSubSubscription(&helix.EventSubSubscription{
-		Type:    eventType,
-		Version: "1",
-		// the bot user and the broadcaster user are the same, assuming that the access token is for the broadcaster
-		Condition: helix.EventSubCondition{
-			UserID:            broadcasterID,
-			BroadcasterUserID: broadcasterID,
-		},
-		Transport: helix.EventSubTransport{
-			Method:   "webhook",
-			Callback: c.webhookURL,
-			Secret:   c.webhookSecret,
-		},
+		Type:      eventType,
+		Version:   version,
+		Condition: condition,
+		Transport: transport,
 	})
 
 	if err != nil {
@@ -163,3 +301,144 @@ func (c *Client) Subscribe(eventType string, broadcasterID string) error {
 
 	return nil
 }
+
+// Subscriptions lists every eventsub subscription of the app, whatever its
+// status or transport.
+func (c *Client) Subscriptions() ([]helix.EventSubSubscription, error) {
+	var subscriptions []helix.EventSubSubscription
+
+	cursor := ""
+	for {
+		res, err := c.appClient.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{
+			After: cursor,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusOK {
+			return nil, errors.Join(errors.New("failed to list subscriptions"), errors.New(res.ErrorMessage))
+		}
+
+		subscriptions = append(subscriptions, res.Data.EventSubSubscriptions...)
+
+		cursor = res.Data.Pagination.Cursor
+		if cursor == "" {
+			return subscriptions, nil
+		}
+	}
+}
+
+// Resubscribe recreates a subscription the collectors subscribed to, with the
+// webhook URL and secret of the client. It returns
+// ErrSubscriptionNotRegistered for the subscriptions created by others.
+func (c *Client) Resubscribe(subscription helix.EventSubSubscription) error {
+	c.registeredMtx.Lock()
+	userID, ok := c.registered[subscriptionKey{eventType: subscription.Type, version: subscription.Version, condition: subscription.Condition}]
+	c.registeredMtx.Unlock()
+
+	if !ok {
+		return ErrSubscriptionNotRegistered
+	}
+
+	return c.SubscribeWithCondition(subscription.Type, subscription.Version, userID, subscription.Condition)
+}
+
+// challengeReceived notifies the self test waiting for the verification
+// challenge of the subscription, if any. The body is restored so the webhook
+// client can verify the challenge.
+func (c *Client) challengeReceived(r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var challenge struct {
+		Subscription struct {
+			ID string `json:"id"`
+		} `json:"subscription"`
+	}
+	if err := json.Unmarshal(body, &challenge); err != nil {
+		return
+	}
+
+	c.challengesMtx.Lock()
+	defer c.challengesMtx.Unlock()
+
+	if received, ok := c.challenges[challenge.Subscription.ID]; ok {
+		close(received)
+		delete(c.challenges, challenge.Subscription.ID)
+	}
+}
+
+// SelfTest creates a throwaway subscription to the user.update event of the
+// user and reports whether its verification challenge reached the webhook
+// within the timeout. Otherwise the subscriptions are never verified and the
+// eventsub collectors never receive an event. The subscription is removed
+// afterwards.
+func (c *Client) SelfTest(userID string, timeout time.Duration) (bool, error) {
+	if c.cl == nil {
+		return false, ErrEventsubClientNotSet
+	}
+
+	res, err := c.appClient.CreateEventSubSubscription(&helix.EventSubSubscription{
+		Type:      "user.update",
+		Version:   "1",
+		Condition: helix.EventSubCondition{UserID: userID},
+		Transport: helix.EventSubTransport{
+			Method:   "webhook",
+			Callback: c.webhookURL,
+			Secret:   c.webhookSecret,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if res.StatusCode != http.StatusAccepted || len(res.Data.EventSubSubscriptions) == 0 {
+		return false, errors.Join(errors.New("failed to create the self test subscription"), errors.New(res.ErrorMessage))
+	}
+
+	id := res.Data.EventSubSubscriptions[0].ID
+	defer func() {
+		if _, err := c.appClient.RemoveEventSubSubscription(id); err != nil {
+			c.logger.Warn("failed to remove the self test subscription", "subscription_id", id, "err", err)
+		}
+	}()
+
+	received := make(chan struct{})
+	c.challengesMtx.Lock()
+	c.challenges[id] = received
+	c.challengesMtx.Unlock()
+
+	defer func() {
+		c.challengesMtx.Lock()
+		delete(c.challenges, id)
+		c.challengesMtx.Unlock()
+	}()
+
+	select {
+	case <-received:
+		return true, nil
+	case <-time.After(timeout):
+	}
+
+	// the challenge may have arrived before the response of the creation, so
+	// before the subscription was waited for, in which case it got enabled
+	subscriptions, err := c.appClient.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range subscriptions.Data.EventSubSubscriptions {
+		if v.ID == id {
+			return v.Status == "enabled", nil
+		}
+	}
+
+	return false, nil
+}
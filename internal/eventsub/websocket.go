@@ -0,0 +1,241 @@
+package eventsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+	"golang.org/x/net/websocket"
+)
+
+// WebsocketURL is the eventsub websocket endpoint of Twitch.
+var WebsocketURL = "wss://eventsub.wss.twitch.tv/ws"
+
+const (
+	// welcomeTimeout is how long to wait for the welcome message of a new
+	// session, which holds the ID the subscriptions are created for
+	welcomeTimeout = 10 * time.Second
+	// keepaliveGrace is added to the keepalive timeout of the session, so a
+	// slow keepalive message is not taken for a lost connection
+	keepaliveGrace = 5 * time.Second
+	// maxReconnectBackoff caps the wait between two attempts to create a new
+	// session once the connection was lost
+	maxReconnectBackoff = time.Minute
+)
+
+// websocketMessage is a message of the eventsub websocket, only the fields
+// the client needs are decoded.
+type websocketMessage struct {
+	Metadata struct {
+		MessageType      string `json:"message_type"`
+		SubscriptionType string `json:"subscription_type"`
+	} `json:"metadata"`
+	Payload struct {
+		Session struct {
+			ID                      string `json:"id"`
+			KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+			ReconnectURL            string `json:"reconnect_url"`
+		} `json:"session"`
+		Subscription struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"subscription"`
+		Event json.RawMessage `json:"event"`
+	} `json:"payload"`
+}
+
+// websocketSession is a connection to the eventsub websocket.
+type websocketSession struct {
+	conn      *websocket.Conn
+	id        string
+	keepalive time.Duration
+}
+
+// NewWebsocket creates an eventsub client receiving the events on a websocket
+// session rather than a webhook, so it works without a publicly reachable
+// URL. Twitch requires a user access token to subscribe over a websocket, so
+// userClient must have one. The session is started by the first subscription,
+// since Twitch closes a session which has no subscription shortly after its
+// welcome message.
+func NewWebsocket(logger *slog.Logger, userClient *helix.Client) (*Client, error) {
+	if userClient == nil || userClient.GetUserAccessToken() == "" {
+		return nil, errors.New("the websocket transport requires a user access token")
+	}
+
+	c := &Client{
+		appClient:  userClient,
+		logger:     logger,
+		websocket:  true,
+		callbacks:  make(map[string][]func(eventRaw json.RawMessage)),
+		registered: make(map[subscriptionKey]string),
+		challenges: make(map[string]chan struct{}),
+	}
+
+	return c, nil
+}
+
+// startSession starts the websocket session unless it is already started, a
+// failed start is attempted again by the next subscription.
+func (c *Client) startSession() error {
+	c.sessionStartMtx.Lock()
+	defer c.sessionStartMtx.Unlock()
+
+	if c.sessionStarted {
+		return nil
+	}
+
+	session, err := c.connect(WebsocketURL)
+	if err != nil {
+		return err
+	}
+
+	c.setSession(session.id)
+	c.connected.Store(true)
+	c.sessionStarted = true
+	go c.run(session)
+
+	return nil
+}
+
+// Connected reports whether the websocket session is connected.
+func (c *Client) Connected() bool {
+	return c.connected.Load()
+}
+
+// Reconnects returns the number of times the websocket moved to a new
+// session, whether Twitch asked for it or the connection was lost.
+func (c *Client) Reconnects() int64 {
+	return c.reconnects.Load()
+}
+
+// connect dials the websocket and waits for the welcome message of the
+// session.
+func (c *Client) connect(url string) (*websocketSession, error) {
+	conn, err := websocket.Dial(url, "", "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(welcomeTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var msg websocketMessage
+	if err := websocket.JSON.Receive(conn, &msg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if msg.Metadata.MessageType != "session_welcome" {
+		conn.Close()
+		return nil, fmt.Errorf("expected a session_welcome message, got %q", msg.Metadata.MessageType)
+	}
+
+	c.logger.Info("eventsub websocket session started", "session_id", msg.Payload.Session.ID)
+
+	return &websocketSession{
+		conn:      conn,
+		id:        msg.Payload.Session.ID,
+		keepalive: time.Duration(msg.Payload.Session.KeepaliveTimeoutSeconds) * time.Second,
+	}, nil
+}
+
+func (c *Client) setSession(id string) {
+	c.sessionMtx.Lock()
+	defer c.sessionMtx.Unlock()
+
+	c.sessionID = id
+}
+
+// run reads the messages of the session until the connection is lost, then
+// starts a new session. The subscriptions are bound to the session they were
+// created for, so every subscription of the collectors is created again for
+// the new one.
+func (c *Client) run(session *websocketSession) {
+	for {
+		session = c.read(session)
+		if session != nil {
+			c.reconnects.Add(1)
+			continue
+		}
+
+		c.connected.Store(false)
+
+		backoff := time.Second
+		for {
+			var err error
+			session, err = c.connect(WebsocketURL)
+			if err == nil {
+				break
+			}
+
+			c.logger.Warn("failed to start an eventsub websocket session", "err", err, "retry_in", backoff)
+			time.Sleep(backoff)
+			backoff = min(2*backoff, maxReconnectBackoff)
+		}
+
+		c.setSession(session.id)
+		c.connected.Store(true)
+		c.reconnects.Add(1)
+		c.resubscribeAll()
+	}
+}
+
+// read dispatches the events of the session. It returns the session to move
+// to when Twitch asks to reconnect, or nil once the connection is lost.
+func (c *Client) read(session *websocketSession) *websocketSession {
+	for {
+		if err := session.conn.SetReadDeadline(time.Now().Add(session.keepalive + keepaliveGrace)); err != nil {
+			c.logger.Warn("eventsub websocket connection lost", "err", err)
+			session.conn.Close()
+			return nil
+		}
+
+		var msg websocketMessage
+		if err := websocket.JSON.Receive(session.conn, &msg); err != nil {
+			c.logger.Warn("eventsub websocket connection lost", "err", err)
+			session.conn.Close()
+			return nil
+		}
+
+		switch msg.Metadata.MessageType {
+		case "notification":
+			c.dispatch(msg.Metadata.SubscriptionType, msg.Payload.Event)
+		case "session_reconnect":
+			// the subscriptions move to the new session, which must be
+			// connected before the current one is closed
+			next, err := c.connect(msg.Payload.Session.ReconnectURL)
+			session.conn.Close()
+			if err != nil {
+				c.logger.Warn("failed to reconnect the eventsub websocket", "err", err)
+				return nil
+			}
+
+			c.setSession(next.id)
+			return next
+		case "revocation":
+			c.logger.Warn("eventsub subscription revoked", "event", msg.Payload.Subscription.Type, "status", msg.Payload.Subscription.Status)
+		}
+	}
+}
+
+// resubscribeAll creates every subscription of the collectors again, for a new
+// session.
+func (c *Client) resubscribeAll() {
+	c.registeredMtx.Lock()
+	registered := make(map[subscriptionKey]string, len(c.registered))
+	for key, userID := range c.registered {
+		registered[key] = userID
+	}
+	c.registeredMtx.Unlock()
+
+	for key, userID := range registered {
+		if err := c.SubscribeWithCondition(key.eventType, key.version, userID, key.condition); err != nil {
+			c.logger.Error("failed to subscribe to event", "event", key.eventType, "user_id", userID, "err", err)
+		}
+	}
+}
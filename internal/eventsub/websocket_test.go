@@ -0,0 +1,70 @@
+package eventsub
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nicklaw5/helix/v2"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebsocketConnectsOnFirstSubscription(t *testing.T) {
+	sessions := atomic.Int32{}
+	wsServer := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		sessions.Add(1)
+		websocket.Message.Send(conn, `{"metadata":{"message_type":"session_welcome"},"payload":{"session":{"id":"session-id","keepalive_timeout_seconds":10}}}`)
+
+		// the session is held open until the client closes it
+		io.Copy(io.Discard, conn)
+	}))
+	defer wsServer.Close()
+
+	previousURL := WebsocketURL
+	WebsocketURL = "ws" + strings.TrimPrefix(wsServer.URL, "http")
+	defer func() { WebsocketURL = previousURL }()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		w.Write([]byte(`{"data":[],"pagination":{}}`))
+	}))
+	defer apiServer.Close()
+
+	userClient, err := helix.NewClient(&helix.Options{
+		ClientID:        "client-id",
+		UserAccessToken: "user-access-token",
+		APIBaseURL:      apiServer.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewWebsocket(slog.New(slog.NewTextHandler(io.Discard, nil)), userClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := sessions.Load(); n != 0 || c.Connected() {
+		t.Fatalf("%d sessions started before any subscription, want 0", n)
+	}
+
+	for _, event := range []string{"channel.raid", "channel.hype_train.begin"} {
+		if err := c.Subscribe(event, "1234"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := sessions.Load(); n != 1 || !c.Connected() {
+		t.Errorf("%d sessions started by the subscriptions, want 1", n)
+	}
+
+	if transport := c.transport(); transport.SessionID != "session-id" {
+		t.Errorf("subscribed for session %q, want %q", transport.SessionID, "session-id")
+	}
+}
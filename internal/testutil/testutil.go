@@ -0,0 +1,59 @@
+// Package testutil provides a fake Helix API serving canned responses, so the
+// collectors can be run without requesting Twitch.
+package testutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// Fixtures maps the path of a Helix endpoint, eg: /streams, to the JSON body
+// it responds with.
+type Fixtures map[string]string
+
+// DefaultFixtures are responses of the endpoints the channel collectors
+// request, for the channel "somechannel" which is live.
+var DefaultFixtures = Fixtures{
+	"/users":              `{"data":[{"id":"1234","login":"somechannel","display_name":"SomeChannel","type":"","broadcaster_type":"partner","view_count":0,"created_at":"2016-01-01T00:00:00Z"}]}`,
+	"/streams":            `{"data":[{"id":"40001","user_id":"1234","user_login":"somechannel","user_name":"SomeChannel","game_id":"509658","game_name":"Just Chatting","type":"live","title":"Hello","viewer_count":42,"started_at":"2026-01-01T00:00:00Z","language":"en","thumbnail_url":"https://static-cdn.jtvnw.net/previews-ttv/live_user_somechannel-{width}x{height}.jpg","tags":["English"]}],"pagination":{}}`,
+	"/clips":              `{"data":[{"id":"AwkwardClip","broadcaster_id":"1234","broadcaster_name":"SomeChannel","creator_id":"5678","creator_name":"Viewer","video_id":"","game_id":"509658","title":"Clip","view_count":10,"created_at":"2026-01-01T00:30:00Z","duration":30}],"pagination":{}}`,
+	"/subscriptions":      `{"data":[{"broadcaster_id":"1234","broadcaster_login":"somechannel","broadcaster_name":"SomeChannel","is_gift":false,"tier":"1000","user_id":"5678","user_login":"viewer","user_name":"Viewer"},{"broadcaster_id":"1234","broadcaster_login":"somechannel","broadcaster_name":"SomeChannel","is_gift":true,"tier":"2000","user_id":"9012","user_login":"other","user_name":"Other"}],"pagination":{},"total":2,"points":3}`,
+	"/channels/followers": `{"data":[{"user_id":"5678","user_login":"viewer","user_name":"Viewer","followed_at":"2025-06-01T00:00:00Z"}],"pagination":{},"total":1}`,
+}
+
+// NewServer starts a fake Helix API responding with the fixtures, endpoints
+// without a fixture respond with an empty list. The server must be closed by
+// the caller.
+func NewServer(fixtures Fixtures) *httptest.Server {
+	return httptest.NewServer(Handler(fixtures))
+}
+
+// Handler returns the handler of the fake Helix API, so tests can wrap it to
+// inspect the requests.
+func Handler(fixtures Fixtures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := fixtures[strings.TrimPrefix(r.URL.Path, "/helix")]
+		if !ok {
+			body = `{"data":[],"pagination":{}}`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Ratelimit-Limit", "800")
+		w.Header().Set("Ratelimit-Remaining", "799")
+		w.Write([]byte(body))
+	})
+}
+
+// NewClient creates a Helix client requesting the fake Helix API of the
+// server.
+func NewClient(server *httptest.Server) (*helix.Client, error) {
+	return helix.NewClient(&helix.Options{
+		ClientID:       "client-id",
+		AppAccessToken: "app-access-token",
+		APIBaseURL:     server.URL,
+		HTTPClient:     server.Client(),
+	})
+}
@@ -0,0 +1,102 @@
+// Package thirdparty requests the emotes of twitch channels from third-party
+// emote providers, which are not part of the Twitch API.
+package thirdparty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	ProviderBTTV = "bttv"
+	ProviderFFZ  = "ffz"
+
+	bttvBaseURL = "https://api.betterttv.net/3"
+	ffzBaseURL  = "https://api.frankerfacez.com/v1"
+)
+
+// Client requests the public APIs of the third-party emote providers.
+type Client struct {
+	httpClient *http.Client
+}
+
+// New creates a Client sending its requests with httpClient.
+func New(httpClient *http.Client) *Client {
+	return &Client{httpClient: httpClient}
+}
+
+// EmoteCounts returns the number of emotes of a twitch channel for each
+// provider, keyed by the provider name.
+func (c *Client) EmoteCounts(broadcasterID string) (map[string]int, error) {
+	bttv, err := c.bttvEmoteCount(broadcasterID)
+	if err != nil {
+		return nil, err
+	}
+
+	ffz, err := c.ffzEmoteCount(broadcasterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]int{
+		ProviderBTTV: bttv,
+		ProviderFFZ:  ffz,
+	}, nil
+}
+
+// bttvEmoteCount counts the emotes uploaded by and shared with a channel on
+// BetterTTV.
+func (c *Client) bttvEmoteCount(broadcasterID string) (int, error) {
+	var user struct {
+		ChannelEmotes []json.RawMessage `json:"channelEmotes"`
+		SharedEmotes  []json.RawMessage `json:"sharedEmotes"`
+	}
+
+	found, err := c.get(bttvBaseURL+"/cached/users/twitch/"+broadcasterID, &user)
+	if err != nil || !found {
+		return 0, err
+	}
+
+	return len(user.ChannelEmotes) + len(user.SharedEmotes), nil
+}
+
+// ffzEmoteCount counts the emotes of the emote set of a channel on
+// FrankerFaceZ.
+func (c *Client) ffzEmoteCount(broadcasterID string) (int, error) {
+	var room struct {
+		Room struct {
+			Set int `json:"set"`
+		} `json:"room"`
+		Sets map[string]struct {
+			Emoticons []json.RawMessage `json:"emoticons"`
+		} `json:"sets"`
+	}
+
+	found, err := c.get(ffzBaseURL+"/room/id/"+broadcasterID, &room)
+	if err != nil || !found {
+		return 0, err
+	}
+
+	return len(room.Sets[fmt.Sprint(room.Room.Set)].Emoticons), nil
+}
+
+// get requests url and decodes the response body into data. Channels unknown
+// to a provider are reported as not found, in which case false is returned.
+func (c *Client) get(url string, data any) (bool, error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return true, json.NewDecoder(resp.Body).Decode(data)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolveSecret returns the secret given by the flag, the environment variable
+// or the file, so secrets can be kept out of the process listing. It fails
+// when the secret is given by more than one of them, since it would be
+// ambiguous which one is used. An empty envVar or path is not a source.
+func resolveSecret(flagName, flagValue, envVar, path string) (string, error) {
+	sources := []string{}
+	value := ""
+
+	if flagValue != "" {
+		sources = append(sources, "--"+flagName)
+		value = flagValue
+	}
+
+	if envVar != "" && os.Getenv(envVar) != "" {
+		sources = append(sources, envVar)
+		value = os.Getenv(envVar)
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		sources = append(sources, path)
+		value = strings.TrimSpace(string(b))
+	}
+
+	if len(sources) > 1 {
+		return "", fmt.Errorf("%s is given by %s, it must be given by only one of them", flagName, strings.Join(sources, " and "))
+	}
+
+	return value, nil
+}
@@ -4,10 +4,17 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
@@ -17,6 +24,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/promslog"
 	"github.com/prometheus/common/promslog/flag"
 	"github.com/prometheus/common/version"
@@ -25,27 +33,56 @@ import (
 )
 
 var (
+	serveCommand = kingpin.Command("serve", "Run the exporter.").Default()
+
 	metricsPath = kingpin.Flag("web.telemetry-path",
 		"Path under which to expose metrics.").
 		Default("/metrics").String()
+	webEnableDebug = kingpin.Flag("web.enable-debug",
+		"Expose the state of every channel at /debug/channels, which includes internal state such as broadcaster IDs.").
+		Default("false").Bool()
+	metricPrefix = kingpin.Flag("web.metric-prefix",
+		"Prefix of the name of every metric, to tell several exporters apart, eg: twitch_a.").
+		Default("twitch").String()
+	cacheWarm = kingpin.Flag("cache.warm",
+		"Resolve the configured channels at startup, before serving metrics, so the first scrape finds them cached.").
+		Default("false").Bool()
+	dryRun = kingpin.Flag("dry-run",
+		"Run every enabled collector once, print the metrics to stdout and exit.").
+		Default("false").Bool()
 
 	// twitch app access token config
 	twitchClientID = kingpin.Flag("twitch.client-id",
-		"Client ID for the Twitch Helix API.").Required().String()
+		"Client ID for the Twitch Helix API, may be set with the TWITCH_CLIENT_ID environment variable instead.").String()
 	twitchClientSecret = kingpin.Flag("twitch.client-secret",
-		"Client Secret for the Twitch Helix API.").String()
+		"Client Secret for the Twitch Helix API, may be set with the TWITCH_CLIENT_SECRET environment variable instead.").String()
+	twitchUserAgent = kingpin.Flag("twitch.user-agent",
+		"User-Agent of the requests to the Twitch API (default: twitch_exporter/<version>).").String()
+	twitchAPIBaseURL = kingpin.Flag("twitch.api-base-url",
+		"Base URL of the Twitch Helix API, eg: to point at the `twitch-cli mock-api` server.").
+		Default(helix.DefaultAPIBaseURL).String()
 
 	// twitch client access token config
 	twitchAccessToken = kingpin.Flag("twitch.access-token",
-		"Access Token for the Twitch Helix API.").String()
+		"Access Token for the Twitch Helix API, may be set with the TWITCH_ACCESS_TOKEN environment variable instead.").String()
+	twitchAccessTokenFile = kingpin.Flag("twitch.access-token-file",
+		"File holding the Access Token for the Twitch Helix API, eg: a mounted Kubernetes secret.").String()
 	twitchRefreshToken = kingpin.Flag("twitch.refresh-token",
 		"Refresh Token for the Twitch Helix API.").String()
+	twitchTokenFile = kingpin.Flag("twitch.token-file",
+		"File holding the access and refresh tokens, as written by the auth command. Renewed tokens are written back to it.").String()
 	eventSubEnabled = kingpin.Flag("eventsub.enabled",
 		"Enable the Twitch Eventsub API.").Default("false").Bool()
+	eventSubTransport = kingpin.Flag("eventsub.transport",
+		"How Twitch delivers the events, webhook requires a publicly reachable URL while websocket works behind NAT but requires a user access token.").
+		Default("webhook").Enum("webhook", "websocket")
 	eventSubWebhookURL = kingpin.Flag("eventsub.webhook-url",
 		"The url your collector will be expected to be hosted at, eg: http://example.svc/eventsub (Must end with `/eventsub`).").Default("").String()
 	eventSubWebhookSecret = kingpin.Flag("eventsub.webhook-secret",
 		"Secure 1-100 character secret for your eventsub validation.").Default("").String()
+	eventSubSelfTestTimeout = kingpin.Flag("eventsub.self-test-timeout",
+		"How long to wait at startup for Twitch to reach the webhook with the challenge of a throwaway subscription, 0 disables the self test.").
+		Default("30s").Duration()
 
 	// collector configs
 	// the twitch channel is a global config for all collectors, and is
@@ -53,8 +90,15 @@ var (
 	// configurations, which are defined within the collector itself.
 	twitchChannel = Channels(kingpin.Flag("twitch.channel",
 		"Name of a Twitch Channel to request metrics."))
+	twitchChannelFile = kingpin.Flag("twitch.channel-file",
+		"Path to a file listing one Twitch Channel per line to request metrics, optionally followed by its group, or to a directory of such files. May be repeated.").Strings()
+	twitchChannelGroup = kingpin.Flag("twitch.channel-group",
+		"Group of a Twitch Channel, added as a group label to its metrics, eg: channel=group.").StringMap()
 )
 
+// tokenRefreshes is created once the metric prefix is known.
+var tokenRefreshes prometheus.Counter
+
 type promHTTPLogger struct {
 	logger *slog.Logger
 }
@@ -70,6 +114,113 @@ func Channels(s kingpin.Settings) (target *collector.ChannelNames) {
 	return target
 }
 
+// readChannelFile reads the channels listed in a file, one per line, and the
+// groups of the channels which are followed by one. Blank lines and lines
+// starting with # are ignored.
+func readChannelFile(path string) ([]string, map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	channels := []string{}
+	groups := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, nil, fmt.Errorf("invalid channel line %q, expected a channel optionally followed by its group", line)
+		}
+
+		channels = append(channels, fields[0])
+		if len(fields) == 2 {
+			groups[strings.ToLower(fields[0])] = fields[1]
+		}
+	}
+
+	return channels, groups, scanner.Err()
+}
+
+// readChannelFiles reads the channels of every file, and of every file of the
+// directories, merging them so each team may own its own channel file. A
+// channel given different groups by two files is an error.
+func readChannelFiles(paths []string) ([]string, map[string]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the entries are sorted by name, so the channels are read in a
+		// stable order
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	channels := collector.ChannelNames{}
+	groups := make(map[string]string)
+	groupFiles := make(map[string]string)
+	for _, file := range files {
+		fileChannels, fileGroups, err := readChannelFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for channel, group := range fileGroups {
+			if other, ok := groups[channel]; ok && other != group {
+				return nil, nil, fmt.Errorf("channel %s is in group %q in %s and in group %q in %s", channel, other, groupFiles[channel], group, file)
+			}
+
+			groups[channel] = group
+			groupFiles[channel] = file
+		}
+
+		channels = mergeChannels(channels, fileChannels)
+	}
+
+	return channels, groups, nil
+}
+
+// mergeChannels appends the channels which are not already part of the channel
+// names, comparing them case insensitively as twitch logins are.
+func mergeChannels(channelNames collector.ChannelNames, channels []string) collector.ChannelNames {
+	seen := make(map[string]bool)
+	merged := collector.ChannelNames{}
+
+	for _, channel := range append([]string(channelNames), channels...) {
+		if seen[strings.ToLower(channel)] {
+			continue
+		}
+
+		seen[strings.ToLower(channel)] = true
+		merged = append(merged, channel)
+	}
+
+	return merged
+}
+
 func init() {
 	prometheus.MustRegister(versioncollector.NewCollector("twitch_exporter"))
 }
@@ -81,61 +232,152 @@ func main() {
 	var webConfig = webflag.AddFlags(kingpin.CommandLine, "0.0.0.0:9184")
 	kingpin.Version(version.Print("twitch_exporter"))
 	kingpin.HelpFlag.Short('h')
-	kingpin.Parse()
+	command := kingpin.Parse()
 
 	logger := promslog.New(promslogConfig)
 	logger.Info("Starting twitch_exporter", "version", version.Info())
 	logger.Info("", "build_context", version.BuildContext())
 
-	var client *helix.Client
+	var clients collector.Clients
 	var err error
 
-	clientType := "app"
+	if err := collector.SetNamespace(*metricPrefix); err != nil {
+		logger.Error("Error setting the metric prefix", "err", err)
+		os.Exit(1)
+	}
+
+	tokenRefreshes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: collector.Namespace(),
+		Name:      "token_refreshes_total",
+		Help:      "Number of times the user access token was renewed with the refresh token.",
+	})
+
+	// secrets may be given by environment variables or files, which unlike
+	// flags do not show up in the process listing
+	*twitchClientID, err = resolveSecret("twitch.client-id", *twitchClientID, "TWITCH_CLIENT_ID", "")
+	if err != nil {
+		logger.Error("Error reading the client ID", "err", err)
+		os.Exit(1)
+	}
+
+	*twitchClientSecret, err = resolveSecret("twitch.client-secret", *twitchClientSecret, "TWITCH_CLIENT_SECRET", "")
+	if err != nil {
+		logger.Error("Error reading the client secret", "err", err)
+		os.Exit(1)
+	}
 
 	if *twitchClientID == "" || *twitchClientSecret == "" {
-		logger.Error("Error creating the client", "err", "client ID and secret are required")
+		logger.Error("Error creating the client", "err", "client ID and secret are required, set them with --twitch.client-id and --twitch.client-secret or TWITCH_CLIENT_ID and TWITCH_CLIENT_SECRET")
 		os.Exit(1)
 	}
 
-	if *twitchAccessToken != "" && *twitchRefreshToken != "" {
-		clientType = "user"
+	if command == authCommand.FullCommand() {
+		if err := runAuth(logger); err != nil {
+			logger.Error("Error obtaining the user access token", "err", err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
 	}
 
-	logger.Info("client type determined", "clientType", clientType)
+	// endpoints which the helix client does not support are requested by the
+	// collectors directly, so they must follow the same base URL, retries and
+	// in flight limit
+	collector.APIBaseURL = *twitchAPIBaseURL
+	collector.UserAgent = userAgent()
+	collector.HTTPClient = collector.NewRefreshingClient(collector.NewCoalescingClient(collector.NewInflightClient(collector.NewRetryClient(logger))))
+	collector.TokenRefresher = func(client *helix.Client) error {
+		if client == clients.User {
+			return refreshUserAccessToken(logger, client)
+		}
+
+		return refreshAppAccessToken(logger, client)
+	}
 
-	switch clientType {
-	case "app":
-		client, err = newClientWithSecret(logger)
+	channelGroups := make(map[string]string)
+	if len(*twitchChannelFile) > 0 {
+		channels, groups, err := readChannelFiles(*twitchChannelFile)
 		if err != nil {
-			logger.Error("Error creating the client", "err", err)
+			logger.Error("Error reading the channel file", "err", err)
+			os.Exit(1)
+		}
+
+		*twitchChannel = mergeChannels(*twitchChannel, channels)
+		channelGroups = groups
+	}
+
+	// the groups given on the command line take precedence over the file
+	for channel, group := range *twitchChannelGroup {
+		channelGroups[strings.ToLower(channel)] = group
+	}
+
+	collector.SetChannelGroups(channelGroups)
+
+	*twitchAccessToken, err = resolveSecret("twitch.access-token", *twitchAccessToken, "TWITCH_ACCESS_TOKEN", *twitchAccessTokenFile)
+	if err != nil {
+		logger.Error("Error reading the access token", "err", err)
+		os.Exit(1)
+	}
+
+	if *twitchTokenFile != "" {
+		if *twitchAccessToken != "" {
+			logger.Error("Error reading the token file", "err", "the access token is given by --twitch.token-file and another source, it must be given by only one of them")
 			os.Exit(1)
 		}
-	case "user":
-		client, err = newClientWithUserAccessToken(logger)
+
+		*twitchAccessToken, *twitchRefreshToken, err = readTokenFile(*twitchTokenFile)
 		if err != nil {
-			logger.Error("Error creating the client", "err", err)
+			logger.Error("Error reading the token file", "err", err)
 			os.Exit(1)
 		}
 	}
 
-	var eventsubClient *eventsub.Client
+	// the app access token is always available, while the user access token
+	// is only used by the collectors requesting private data, such as
+	// subscriber counts
+	clients.App, err = newClientWithSecret(logger)
+	if err != nil {
+		logger.Error("Error creating the client", "err", err)
+		os.Exit(1)
+	}
 
-	if *eventSubEnabled {
-		logger.Info("eventsub endpoint enabled", "endpoint", "/eventsub")
+	if *twitchAccessToken != "" && *twitchRefreshToken != "" {
+		clients.User, err = newClientWithUserAccessToken(logger)
+		if err != nil {
+			logger.Error("Error creating the client", "err", err)
+			os.Exit(1)
+		}
+	}
 
-		var appClient *helix.Client
+	logger.Info("clients created", "user_access_token", clients.User != nil)
 
-		// eventsub requires an app client to create webhooks, but we may have created a user client
-		// beforehand for subscription metrics, so just check and create the app client if needed
-		if clientType == "user" {
-			appClient, err = newClientWithSecret(logger)
-			if err != nil {
-				logger.Error("Error creating the client", "err", err)
-				os.Exit(1)
-			}
+	if *cacheWarm {
+		// a failure is only logged, the caches are then filled by the scrapes
+		notFound, err := collector.WarmCache(clients.App, *twitchChannel)
+		if err != nil {
+			logger.Error("Error warming the cache", "err", err)
 		} else {
-			appClient = client
+			logger.Info("cache warmed", "channels", len(*twitchChannel), "warmed", len(*twitchChannel)-len(notFound))
+			if len(notFound) > 0 {
+				logger.Warn("channels not found while warming the cache", "channels", notFound)
+			}
+		}
+	}
+
+	var eventsubClient *eventsub.Client
+
+	if *eventSubEnabled && *eventSubTransport == "websocket" {
+		logger.Info("eventsub websocket enabled")
+
+		// subscriptions over a websocket must be created with a user access
+		// token, the one of the user client
+		eventsubClient, err = eventsub.NewWebsocket(logger, clients.User)
+		if err != nil {
+			logger.Error("Error creating the eventsub client", "err", err)
+			os.Exit(1)
 		}
+	} else if *eventSubEnabled {
+		logger.Info("eventsub endpoint enabled", "endpoint", "/eventsub")
 
 		if *eventSubWebhookURL == "" || *eventSubWebhookSecret == "" {
 			logger.Error("Error creating the eventsub client", "err", "webhook URL and secret are required")
@@ -148,7 +390,8 @@ func main() {
 			*eventSubWebhookURL,
 			*eventSubWebhookSecret,
 			logger,
-			appClient,
+			// eventsub requires an app client to create webhooks
+			clients.App,
 		)
 
 		if err != nil {
@@ -160,7 +403,7 @@ func main() {
 		http.HandleFunc("/eventsub", eventsubClient.Handler())
 	}
 
-	exporter, err := collector.NewExporter(logger, client, eventsubClient, *twitchChannel)
+	exporter, err := collector.NewExporter(logger, clients, eventsubClient, *twitchChannel)
 	if err != nil {
 		logger.Error("Error creating the exporter", "err", err)
 		os.Exit(1)
@@ -168,12 +411,56 @@ func main() {
 
 	r := prometheus.NewRegistry()
 	r.MustRegister(exporter)
+	r.MustRegister(tokenRefreshes)
+
+	if eventsubClient != nil && *eventSubTransport == "websocket" {
+		r.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: collector.Namespace(),
+			Name:      "eventsub_ws_connected",
+			Help:      "Whether the eventsub websocket session is connected.",
+		}, func() float64 {
+			if eventsubClient.Connected() {
+				return 1
+			}
+			return 0
+		}))
+		r.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: collector.Namespace(),
+			Name:      "eventsub_ws_reconnects_total",
+			Help:      "Number of times the eventsub websocket moved to a new session, whether Twitch asked for it or the connection was lost.",
+		}, func() float64 {
+			return float64(eventsubClient.Reconnects())
+		}))
+	}
+
+	if *dryRun {
+		if err := printMetrics(r); err != nil {
+			logger.Error("Error during dry run", "err", err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
 
 	http.Handle(*metricsPath, promhttp.HandlerFor(r, promhttp.HandlerOpts{
 		ErrorLog:      promHTTPLogger{logger: logger},
 		ErrorHandling: promhttp.ContinueOnError,
+		// negotiated through the Accept header, so scrapers which do not ask
+		// for OpenMetrics keep getting the text format. the const metrics
+		// have no created timestamp, so there are no _created samples
+		EnableOpenMetrics: true,
 	}))
 
+	http.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	http.Handle("/healthz", newReadinessHandler(clients))
+
+	if *webEnableDebug {
+		logger.Info("debug endpoint enabled", "endpoint", "/debug/channels")
+		http.HandleFunc("/debug/channels", debugChannelsHandler(exporter))
+	}
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write([]byte(`<html>
              <head><title>Twitch Exporter</title></head>
@@ -189,43 +476,200 @@ func main() {
 		}
 	})
 
+	if eventsubClient != nil && *eventSubTransport == "webhook" && *eventSubSelfTestTimeout > 0 {
+		// the challenge is sent to the webhook, so the test runs once the
+		// server is started
+		go testEventsubWebhook(logger, r, clients.App, eventsubClient, *twitchChannel)
+	}
+
 	srv := &http.Server{}
-	if err := web.ListenAndServe(srv, webConfig, logger); err != nil {
+	if err := listenAndServe(srv, webConfig, logger); err != nil {
 		logger.Error("Error starting HTTP server", "err", err)
 		os.Exit(1)
 	}
 }
 
-func refreshAppAccessToken(logger *slog.Logger, client *helix.Client) {
+// testEventsubWebhook checks whether Twitch can reach the webhook, exporting
+// the result as a gauge. An unreachable webhook never verifies the
+// subscriptions, so the eventsub collectors would otherwise silently report
+// nothing.
+func testEventsubWebhook(logger *slog.Logger, r *prometheus.Registry, client *helix.Client, eventsubClient *eventsub.Client, channelNames collector.ChannelNames) {
+	if len(channelNames) == 0 {
+		logger.Warn("Skipping the eventsub webhook self test", "err", "a channel is required to subscribe to")
+		return
+	}
+
+	usersResp, err := client.GetUsers(&helix.UsersParams{Logins: channelNames[:1]})
+	if err != nil || len(usersResp.Data.Users) == 0 {
+		logger.Error("Error running the eventsub webhook self test", "err", "could not look up channel "+channelNames[0])
+		return
+	}
+
+	reachable, err := eventsubClient.SelfTest(usersResp.Data.Users[0].ID, *eventSubSelfTestTimeout)
+	if err != nil {
+		logger.Error("Error running the eventsub webhook self test", "err", err)
+		return
+	}
+
+	webhookReachable := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: collector.Namespace(),
+		Name:      "eventsub_webhook_reachable",
+		Help:      "Whether Twitch reached the eventsub webhook with the challenge of a subscription at startup.",
+	})
+	r.MustRegister(webhookReachable)
+
+	if !reachable {
+		logger.Error("Twitch could not reach the eventsub webhook, the eventsub collectors will not receive any event", "webhook_url", *eventSubWebhookURL, "timeout", *eventSubSelfTestTimeout)
+		return
+	}
+
+	webhookReachable.Set(1)
+	logger.Info("eventsub webhook reachable", "webhook_url", *eventSubWebhookURL)
+}
+
+// listenAndServe serves on every listen address like web.ListenAndServe, and
+// also accepts unix sockets given as unix:///path/to/socket, which the exporter
+// toolkit does not support. Every handler is registered on the default mux, so
+// they are all served on every listener.
+func listenAndServe(srv *http.Server, flags *web.FlagConfig, logger *slog.Logger) error {
+	if *flags.WebSystemdSocket || !slices.ContainsFunc(*flags.WebListenAddresses, isUnixSocket) {
+		return web.ListenAndServe(srv, flags, logger)
+	}
+
+	listeners := make([]net.Listener, 0, len(*flags.WebListenAddresses))
+	for _, address := range *flags.WebListenAddresses {
+		network := "tcp"
+		if isUnixSocket(address) {
+			network = "unix"
+			address = strings.TrimPrefix(address, "unix://")
+		}
+
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+
+		listeners = append(listeners, listener)
+	}
+
+	return web.ServeMultiple(listeners, srv, flags, logger)
+}
+
+func isUnixSocket(address string) bool {
+	return strings.HasPrefix(address, "unix://")
+}
+
+// printMetrics gathers the metrics of the registry once and writes them to
+// stdout in the text exposition format. An error is returned if any collector
+// failed, so the dry run can be used as a smoke test.
+func printMetrics(r *prometheus.Registry) error {
+	mfs, err := r.Gather()
+	if err != nil {
+		return err
+	}
+
+	enc := expfmt.NewEncoder(os.Stdout, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+
+	failed := []string{}
+	for _, mf := range mfs {
+		if mf.GetName() != prometheus.BuildFQName(collector.Namespace(), "scrape", "collector_success") {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() != 1 {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "collector" {
+						failed = append(failed, l.GetValue())
+					}
+				}
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("collectors failed: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+func refreshAppAccessToken(logger *slog.Logger, client *helix.Client) error {
 	logger.Info("Refreshing app access token")
 	appAccessToken, err := client.RequestAppAccessToken([]string{})
 	if err != nil {
 		logger.Error("Error getting app access token", "err", err)
-		return
+		return err
 	}
 
 	if appAccessToken.ErrorStatus != 0 {
 		logger.Error("Error getting app access token", "err", appAccessToken.ErrorMessage)
-		return
+		return errors.New(appAccessToken.ErrorMessage)
 	}
 
 	client.SetAppAccessToken(appAccessToken.Data.AccessToken)
+	return nil
 }
 
-func refreshUserAccessToken(logger *slog.Logger, client *helix.Client) {
+// refreshTokenMtx serializes the renewals of the user access token, since the
+// refresh token changes with every renewal.
+var refreshTokenMtx sync.Mutex
+
+func refreshUserAccessToken(logger *slog.Logger, client *helix.Client) error {
+	refreshTokenMtx.Lock()
+	defer refreshTokenMtx.Unlock()
+
 	logger.Info("Refreshing user access token")
-	userAccessToken, err := client.RefreshUserAccessToken(client.GetRefreshToken())
+	userAccessToken, err := client.RefreshUserAccessToken(*twitchRefreshToken)
 	if err != nil {
 		logger.Error("Error getting user access token", "err", err)
-		return
+		return err
 	}
 
 	if userAccessToken.ErrorStatus != 0 {
 		logger.Error("Error getting user access token", "err", userAccessToken.ErrorMessage)
-		return
+		return errors.New(userAccessToken.ErrorMessage)
 	}
 
 	client.SetUserAccessToken(userAccessToken.Data.AccessToken)
+	*twitchRefreshToken = userAccessToken.Data.RefreshToken
+	onUserAccessTokenRefreshed(logger, userAccessToken.Data.AccessToken, userAccessToken.Data.RefreshToken)
+	return nil
+}
+
+// onUserAccessTokenRefreshed counts the renewal of the user access token, and
+// writes the renewed tokens back to the token file so they survive a restart,
+// since the previous refresh token may no longer be valid.
+func onUserAccessTokenRefreshed(logger *slog.Logger, accessToken, refreshToken string) {
+	tokenRefreshes.Inc()
+
+	if *twitchTokenFile == "" {
+		return
+	}
+
+	if err := writeTokenFile(*twitchTokenFile, accessToken, refreshToken); err != nil {
+		logger.Warn("Error writing the renewed tokens to the token file", "err", err)
+	}
+}
+
+// userAgent returns the User-Agent of the requests to the Twitch API, so the
+// traffic of the exporter can be identified.
+func userAgent() string {
+	if *twitchUserAgent != "" {
+		return *twitchUserAgent
+	}
+
+	if version.Version != "" {
+		return "twitch_exporter/" + version.Version
+	}
+
+	return "twitch_exporter"
 }
 
 // newClientWithSecret creates a new Twitch client with the use of an app access
@@ -234,6 +678,9 @@ func newClientWithSecret(logger *slog.Logger) (*helix.Client, error) {
 	client, err := helix.NewClient(&helix.Options{
 		ClientID:     *twitchClientID,
 		ClientSecret: *twitchClientSecret,
+		APIBaseURL:   *twitchAPIBaseURL,
+		HTTPClient:   collector.HTTPClient,
+		UserAgent:    userAgent(),
 	})
 
 	if err != nil {
@@ -250,20 +697,25 @@ func newClientWithSecret(logger *slog.Logger) (*helix.Client, error) {
 		}
 	}(logger, refreshTicker, client)
 
+	collector.RegisterTokenClient(client)
+
 	return client, nil
 }
 
 // newClientWithUserAccessToken creates a new Twitch client with a user access token.
 // this is required for private data, such as subscriber counts.
 func newClientWithUserAccessToken(logger *slog.Logger) (*helix.Client, error) {
-	// providing a refresh token allows the helix client to refresh the access
-	// token when it expires. this is done automatically when using the helix
-	// client.
+	// the refresh token is not given to the helix client, which would renew
+	// the access token on every unauthorized response without bound. the
+	// token is renewed by the collector.RefreshingClient instead, which
+	// retries only once.
 	client, err := helix.NewClient(&helix.Options{
 		ClientID:        *twitchClientID,
 		ClientSecret:    *twitchClientSecret,
 		UserAccessToken: *twitchAccessToken,
-		RefreshToken:    *twitchRefreshToken,
+		APIBaseURL:      *twitchAPIBaseURL,
+		HTTPClient:      collector.HTTPClient,
+		UserAgent:       userAgent(),
 	})
 
 	if err != nil {
@@ -283,5 +735,7 @@ func newClientWithUserAccessToken(logger *slog.Logger) (*helix.Client, error) {
 		}
 	}(logger, refreshTicker, client)
 
+	collector.RegisterTokenClient(client)
+
 	return client, nil
 }
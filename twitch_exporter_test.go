@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/damoun/twitch_exporter/collector"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/prometheus/common/version"
+)
+
+// recordingClient answers every request with an access token and an empty
+// list, recording the User-Agent of the requests.
+type recordingClient struct {
+	mtx        sync.Mutex
+	userAgents []string
+}
+
+func (c *recordingClient) Do(req *http.Request) (*http.Response, error) {
+	c.mtx.Lock()
+	c.userAgents = append(c.userAgents, req.Header.Get("User-Agent"))
+	c.mtx.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"access_token":"token","data":[],"pagination":{}}`)),
+	}, nil
+}
+
+func TestClientUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		version   string
+		want      string
+	}{
+		{name: "flag", userAgent: "custom/1.0", version: "1.2.3", want: "custom/1.0"},
+		{name: "version", version: "1.2.3", want: "twitch_exporter/1.2.3"},
+		{name: "no version", want: "twitch_exporter"},
+	}
+
+	previousClientID, previousUserAgent, previousVersion, previousClient := *twitchClientID, *twitchUserAgent, version.Version, collector.HTTPClient
+	defer func() {
+		*twitchClientID, *twitchUserAgent, version.Version, collector.HTTPClient = previousClientID, previousUserAgent, previousVersion, previousClient
+	}()
+	*twitchClientID = "client-id"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*twitchUserAgent, version.Version = tt.userAgent, tt.version
+
+			recorder := &recordingClient{}
+			collector.HTTPClient = recorder
+
+			client, err := newClientWithSecret(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.GetStreams(&helix.StreamsParams{UserLogins: []string{"somechannel"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			// the app access token request and the streams request
+			if len(recorder.userAgents) != 2 {
+				t.Fatalf("got %d requests, want 2", len(recorder.userAgents))
+			}
+
+			for _, got := range recorder.userAgents {
+				if got != tt.want {
+					t.Errorf("User-Agent = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}